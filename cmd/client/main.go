@@ -9,16 +9,28 @@ import (
 	"io"
 	"log"
 	"os"
+	"slices"
+	"strings"
 )
 
+// pushCommands переводят соединение в режим, в котором сервер сам присылает сообщения
+var pushCommands = []string{"SUBSCRIBE", "NOTIFY", "MONITOR"}
+
 // Клиент максимально колхозный, т.к предполагается что у нашей БД может быть множество клиентов и качество их гарантировать нельзя
 // Поэтому тут нет никаких ограничений и проверок на что либо
 func main() {
 	logger, _ := zap.NewProduction()
 
 	address := flag.String("address", "localhost:3223", "tcp server address")
+	encodingName := flag.String("encoding", "text", "response encoding: text or json")
+	flag.Parse()
+
+	encoding, err := network.ParseEncoding(*encodingName)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	client, err := network.NewTCPClient(*address)
+	client, err := network.NewTCPClient(*address, encoding)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -59,5 +71,32 @@ func main() {
 		}
 
 		fmt.Println(string(response))
+
+		// После подписки или MONITOR клиент только печатает сообщения, пока соединение не закроется
+		if command := strings.Fields(queryString); len(command) > 0 && slices.Contains(pushCommands, command[0]) {
+			decoded, err := network.DecodeResponse(client.Encoding(), response)
+			if err == nil && !decoded.IsError() {
+				receive(logger, client)
+				return
+			}
+		}
+	}
+}
+
+func receive(logger *zap.Logger, client *network.TCPClient) {
+	for {
+		message, err := client.Receive()
+		if err != nil {
+			logger.Error("cannot receive message", zap.Error(err))
+			return
+		}
+
+		encoded, err := network.EncodeResponse(client.Encoding(), message)
+		if err != nil {
+			logger.Error("cannot encode message", zap.Error(err))
+			return
+		}
+
+		fmt.Println(string(encoded))
 	}
 }
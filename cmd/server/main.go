@@ -1,10 +1,12 @@
 package main
 
 import (
+	"concurrency_hw/internal/cdc"
 	"concurrency_hw/internal/config"
 	"concurrency_hw/internal/creator"
 	"concurrency_hw/internal/database"
 	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/metrics"
 	"context"
 	"errors"
 	"go.uber.org/zap"
@@ -34,11 +36,26 @@ func main() {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
 
-	server, err := network.NewTCPServer(logger, conf.NetworkConfig, db.Execute)
+	limiter := network.NewConnectionLimiter(conf.NetworkConfig.MaxConnections)
+
+	rateLimiter, err := network.NewRateLimiter(conf.NetworkConfig.RateLimit)
+	if err != nil {
+		logger.Fatal("Failed to create rate limiter", zap.Error(err))
+	}
+
+	server, err := network.NewTCPServer(logger, conf.NetworkConfig, limiter, rateLimiter,
+		initializer.ClientRegistry(), db.Execute)
 	if err != nil {
 		logger.Fatal("Failed to create server", zap.Error(err))
 	}
 
+	registry := initializer.Metrics()
+	if registry != nil {
+		metrics.RegisterConnections(registry, limiter, initializer.ClientRegistry())
+		metrics.RegisterRateLimiter(registry, rateLimiter)
+		metrics.RegisterTCPServer(registry, server)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
 		if err := server.Run(ctx); err != nil {
@@ -46,9 +63,85 @@ func main() {
 		}
 	}()
 
+	if conf.NetworkConfig.HTTPAddress != "" {
+		httpServer, err := network.NewHTTPServer(logger, conf.NetworkConfig, limiter, rateLimiter, db.Execute)
+		if err != nil {
+			logger.Fatal("Failed to create http gateway", zap.Error(err))
+		}
+
+		if registry != nil {
+			metrics.RegisterHTTPServer(registry, httpServer)
+		}
+
+		go func() {
+			if err := httpServer.Run(ctx); err != nil {
+				logger.Fatal("Failed to start http gateway", zap.Error(err))
+			}
+		}()
+	}
+
+	if conf.CDCConfig != nil && conf.CDCConfig.Enabled {
+		runCDC(ctx, logger, initializer, conf.CDCConfig)
+	}
+
+	if registry != nil {
+		runMetrics(ctx, logger, registry, conf.MetricsConfig.Address)
+	}
+
 	shutdown(logger, db, cancel)
 }
 
+func runCDC(ctx context.Context, logger *zap.Logger, initializer *creator.Creator, conf *config.CDCConfig) {
+	tailer, err := initializer.CreateCDCTailer()
+	if err != nil {
+		logger.Fatal("Failed to create cdc tailer", zap.Error(err))
+	}
+
+	if conf.FilePath != "" {
+		sink, err := cdc.NewFileSink(logger, tailer, conf.FilePath)
+		if err != nil {
+			logger.Fatal("Failed to create cdc file sink", zap.Error(err))
+		}
+
+		go func() {
+			if err := sink.Run(ctx); err != nil {
+				logger.Error("cdc file sink stopped", zap.Error(err))
+			}
+		}()
+	}
+
+	if conf.Address != "" {
+		authenticator, err := initializer.CreateAuthenticator()
+		if err != nil {
+			logger.Fatal("Failed to create authenticator", zap.Error(err))
+		}
+
+		server, err := cdc.NewServer(logger, tailer, authenticator)
+		if err != nil {
+			logger.Fatal("Failed to create cdc server", zap.Error(err))
+		}
+
+		go func() {
+			if err := server.Run(ctx, conf.Address); err != nil {
+				logger.Fatal("Failed to start cdc server", zap.Error(err))
+			}
+		}()
+	}
+}
+
+func runMetrics(ctx context.Context, logger *zap.Logger, registry *metrics.Registry, address string) {
+	server, err := metrics.NewServer(logger, registry)
+	if err != nil {
+		logger.Fatal("Failed to create metrics server", zap.Error(err))
+	}
+
+	go func() {
+		if err := server.Run(ctx, address); err != nil {
+			logger.Fatal("Failed to start metrics server", zap.Error(err))
+		}
+	}()
+}
+
 func createLogger(conf *config.LoggingConfig) *zap.Logger {
 	encoderCfg := zap.NewProductionEncoderConfig()
 	encoderCfg.TimeKey = "timestamp"
@@ -25,50 +25,51 @@ func TestTCPServer(t *testing.T) {
 	time.Sleep(5 * time.Second)
 	cli := getClient(address)
 
-	response, err := cli.Execute("GET q")
-	fmt.Println(string(response))
+	response, err := cli.Query("GET q")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, fmt.Sprintf(network.GetResult, ""), string(response))
+	assert.Equal(t, network.NotFoundResponse(), response)
 
-	response, err = cli.Execute("SET q 1")
-	fmt.Println(string(response))
+	response, err = cli.Query("SET q 1")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, network.SuccessCommand, string(response))
+	assert.Equal(t, network.OKResponse(), response)
 
-	response, err = cli.Execute("SET w 2")
-	fmt.Println(string(response))
+	response, err = cli.Query("SET w 2")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, network.SuccessCommand, string(response))
+	assert.Equal(t, network.OKResponse(), response)
 
-	response, err = cli.Execute("GET q")
-	fmt.Println(string(response))
+	response, err = cli.Query("GET q")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, fmt.Sprintf(network.GetResult, "1"), string(response))
+	assert.Equal(t, network.ValueResponse("1"), response)
 
-	response, err = cli.Execute("GET w")
-	fmt.Println(string(response))
+	response, err = cli.Query("GET w")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, fmt.Sprintf(network.GetResult, "2"), string(response))
+	assert.Equal(t, network.ValueResponse("2"), response)
 
-	response, err = cli.Execute("DEL w")
-	fmt.Println(string(response))
+	response, err = cli.Query("DEL w")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, network.SuccessCommand, string(response))
+	assert.Equal(t, network.OKResponse(), response)
 
-	response, err = cli.Execute("GET q")
-	fmt.Println(string(response))
+	response, err = cli.Query("GET q")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, fmt.Sprintf(network.GetResult, "1"), string(response))
+	assert.Equal(t, network.ValueResponse("1"), response)
 
-	response, err = cli.Execute("GET w")
-	fmt.Println(string(response))
+	response, err = cli.Query("GET w")
+	fmt.Println(response)
 	require.NoError(t, err)
-	assert.Equal(t, fmt.Sprintf(network.GetResult, ""), string(response))
+	assert.Equal(t, network.NotFoundResponse(), response)
 
 	// Проверка WAL
 	for range 1000 {
-		_, err := cli.Execute(fmt.Sprintf("SET wal %v", longValue))
+		response, err := cli.Query(fmt.Sprintf("SET wal %v", longValue))
 		require.NoError(t, err)
+		assert.Equal(t, network.OKResponse(), response)
 	}
 
 	require.NoError(t, cli.Disconnect())
@@ -79,7 +80,7 @@ func TestTCPServer(t *testing.T) {
 }
 
 func getClient(address string) *network.TCPClient {
-	client, err := network.NewTCPClient(address)
+	client, err := network.NewTCPClient(address, network.JSONEncoding)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,73 @@
+package cdc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"go.uber.org/zap"
+)
+
+const offsetFileSuffix = ".offset"
+
+// FileSink дописывает изменения в файл в формате JSON lines. Позиция хранится рядом с файлом
+// и сохраняется после записи каждой пачки, поэтому после сбоя часть записей может повториться, но не потеряться
+type FileSink struct {
+	logger  *zap.Logger
+	tailer  *Tailer
+	path    string
+	offsets *OffsetStore
+}
+
+func NewFileSink(logger *zap.Logger, tailer *Tailer, path string) (*FileSink, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+	if path == "" {
+		return nil, errors.New("cdc file path cannot be empty")
+	}
+
+	return &FileSink{
+		logger:  logger,
+		tailer:  tailer,
+		path:    path,
+		offsets: NewOffsetStore(path + offsetFileSuffix),
+	}, nil
+}
+
+func (s *FileSink) Run(ctx context.Context) error {
+	from, err := s.offsets.Load()
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	s.logger.Info("cdc file sink started", zap.String("path", s.path), zap.Stringer("from", from))
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+
+	return s.tailer.Run(ctx, from, func(records []Record) error {
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		if err := file.Sync(); err != nil {
+			return err
+		}
+
+		return s.offsets.Save(records[len(records)-1].Next)
+	})
+}
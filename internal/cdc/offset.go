@@ -0,0 +1,53 @@
+package cdc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// OffsetStore хранит позицию, до которой потребитель обработал изменения
+type OffsetStore struct {
+	path string
+}
+
+func NewOffsetStore(path string) *OffsetStore {
+	return &OffsetStore{path: path}
+}
+
+// Load возвращает сохраненную позицию или начало WAL, если позиция еще не сохранялась
+func (s *OffsetStore) Load() (Position, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Position{}, nil
+	}
+	if err != nil {
+		return Position{}, err
+	}
+
+	return ParsePosition(strings.TrimSpace(string(data)))
+}
+
+// Save атомарно заменяет файл позиции, чтобы сбой во время записи не испортил сохраненное значение
+func (s *OffsetStore) Save(position Position) error {
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.WriteString(position.String() + "\n")
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+
+	return err
+}
@@ -0,0 +1,179 @@
+// Package cdc - change data capture: чтение WAL по мере записи сегментов и выгрузка изменений в приемники
+package cdc
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	OpSet   = "set"
+	OpDel   = "del"
+	OpHSet  = "hset"
+	OpHDel  = "hdel"
+	OpLPush = "lpush"
+	OpRPush = "rpush"
+	OpLPop  = "lpop"
+	OpRPop  = "rpop"
+	OpSAdd  = "sadd"
+	OpSRem  = "srem"
+	OpZAdd  = "zadd"
+	OpZRem  = "zrem"
+
+	OpFlushDB  = "flushdb"
+	OpFlushAll = "flushall"
+	OpSwapDB   = "swapdb"
+)
+
+// Position - позиция в WAL: номер сегмента и смещение в байтах от начала сегмента.
+// В текстовом виде записывается как "segment/offset"
+type Position struct {
+	Segment int
+	Offset  int64
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d/%d", p.Segment, p.Offset)
+}
+
+func ParsePosition(value string) (Position, error) {
+	segment, offset, found := strings.Cut(value, "/")
+	if !found {
+		return Position{}, fmt.Errorf("malformed wal position: %s", value)
+	}
+
+	segmentNum, err := strconv.Atoi(segment)
+	if err != nil || segmentNum < 0 {
+		return Position{}, fmt.Errorf("malformed wal position segment: %s", value)
+	}
+
+	offsetNum, err := strconv.ParseInt(offset, 10, 64)
+	if err != nil || offsetNum < 0 {
+		return Position{}, fmt.Errorf("malformed wal position offset: %s", value)
+	}
+
+	return Position{Segment: segmentNum, Offset: offsetNum}, nil
+}
+
+func (p Position) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *Position) UnmarshalText(data []byte) error {
+	position, err := ParsePosition(string(data))
+	if err != nil {
+		return err
+	}
+
+	*p = position
+	return nil
+}
+
+// Record - изменение одного ключа. LSN - позиция записи в WAL, Next - позиция, с которой потребитель продолжает чтение.
+// MSET и MDEL дают по записи на ключ с общим LSN; Next указывает на следующую запись WAL только у последней из них,
+// у остальных Next == LSN, чтобы при продолжении чтения команда не потерялась частично, а прочиталась заново.
+// HSET и HDEL так же дают по записи на поле хеша, поле указывается в Field, LPUSH и RPUSH - по записи на элемент.
+// SADD, SREM, ZADD и ZREM дают по записи на элемент множества в Field, у ZADD в Value - оценка.
+// Элементы, которые сразу забрал BLPOP, в WAL и в CDC не попадают.
+// DB - номер базы. FLUSHDB и FLUSHALL дают запись без ключа, SWAPDB - запись с номером второй базы в Value.
+// Команды встраивающего приложения (database.RegisterCommand) записей не дают: их формат известен только приложению
+type Record struct {
+	LSN   Position `json:"lsn"`
+	Next  Position `json:"next"`
+	DB    int      `json:"db"`
+	Op    string   `json:"op"`
+	Key   string   `json:"key"`
+	Field string   `json:"field,omitempty"`
+	Value *string  `json:"value,omitempty"`
+}
+
+type QueryParser interface {
+	ParseQuery(queryString string) (compute.Query, error)
+}
+
+func newRecords(parser QueryParser, record string, lsn, next Position) ([]Record, error) {
+	db, query, err := compute.DecodeRecord(record)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decode wal record at %s: %w", lsn, err)
+	}
+
+	parsed, err := parser.ParseQuery(query)
+	if errors.Is(err, compute.ErrUnknownCommand) {
+		// Команда встраивающего приложения, не зарегистрированная в процессе CDC
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse wal record at %s: %w", lsn, err)
+	}
+
+	var records []Record
+	args := parsed.Args
+
+	switch parsed.CommandId {
+	case compute.SetCommandId, compute.MSetCommandId:
+		for i := 0; i+1 < len(args); i += 2 {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: OpSet, Key: args[i], Value: &args[i+1]})
+		}
+	case compute.DelCommandId, compute.MDelCommandId:
+		for _, key := range args {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: OpDel, Key: key})
+		}
+	case compute.HSetCommandId:
+		for i := 1; i+1 < len(args); i += 2 {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: OpHSet, Key: args[0], Field: args[i], Value: &args[i+1]})
+		}
+	case compute.HDelCommandId:
+		for _, field := range args[1:] {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: OpHDel, Key: args[0], Field: field})
+		}
+	case compute.LPushCommandId, compute.RPushCommandId:
+		op := OpLPush
+		if parsed.CommandId == compute.RPushCommandId {
+			op = OpRPush
+		}
+		for i := range args[1:] {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: op, Key: args[0], Value: &args[i+1]})
+		}
+	case compute.LPopCommandId:
+		records = append(records, Record{LSN: lsn, Next: lsn, Op: OpLPop, Key: args[0]})
+	case compute.RPopCommandId:
+		records = append(records, Record{LSN: lsn, Next: lsn, Op: OpRPop, Key: args[0]})
+	case compute.SAddCommandId, compute.SRemCommandId, compute.ZRemCommandId:
+		op := OpSAdd
+		switch parsed.CommandId {
+		case compute.SRemCommandId:
+			op = OpSRem
+		case compute.ZRemCommandId:
+			op = OpZRem
+		}
+		for _, member := range args[1:] {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: op, Key: args[0], Field: member})
+		}
+	case compute.ZAddCommandId:
+		for i := 1; i+1 < len(args); i += 2 {
+			records = append(records, Record{LSN: lsn, Next: lsn, Op: OpZAdd, Key: args[0], Field: args[i+1], Value: &args[i]})
+		}
+	case compute.FlushDBCommandId:
+		records = append(records, Record{LSN: lsn, Next: lsn, Op: OpFlushDB})
+	case compute.FlushAllCommandId:
+		records = append(records, Record{LSN: lsn, Next: lsn, Op: OpFlushAll})
+	case compute.SwapDBCommandId:
+		first, err := strconv.Atoi(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("malformed database index in wal record at %s: %s", lsn, query)
+		}
+		db = first
+		records = append(records, Record{LSN: lsn, Next: lsn, Op: OpSwapDB, Value: &args[1]})
+	default:
+		return nil, nil
+	}
+
+	for i := range records {
+		records[i].DB = db
+	}
+	records[len(records)-1].Next = next
+	return records, nil
+}
@@ -0,0 +1,211 @@
+package cdc
+
+import (
+	"bufio"
+	"concurrency_hw/internal/database/auth"
+	"concurrency_hw/internal/database/compute"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const handshakeTimeout = 5 * time.Second
+
+// Permission - право в списке команд пользователя, без которого подписка на CDC запрещена
+const Permission = "CDC"
+
+// Server - tcp-эндпоинт для подписчиков CDC. Подписчик отправляет строку с позицией, с которой
+// продолжить чтение ("segment/offset", пустая строка - с начала WAL), и получает изменения в формате JSON lines.
+// Позицию подписчик хранит сам: это поле next последней обработанной записи.
+// С включенной аутентификацией позиции предшествует строка "AUTH user password": подписчик получает только
+// записи ключей, доступных ему на чтение, а FLUSHDB, FLUSHALL и SWAPDB - только при доступе ко всем ключам.
+// Без аутентификации изменения получает любой, кто подключился к адресу
+type Server struct {
+	logger        *zap.Logger
+	tailer        *Tailer
+	authenticator *auth.Authenticator
+}
+
+func NewServer(logger *zap.Logger, tailer *Tailer, authenticator *auth.Authenticator) (*Server, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	return &Server{
+		logger:        logger,
+		tailer:        tailer,
+		authenticator: authenticator,
+	}, nil
+}
+
+func (s *Server) Run(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener)
+}
+
+// Serve обслуживает подписчиков до отмены контекста и дожидается завершения их соединений
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	s.logger.Info("cdc server started", zap.String("address", listener.Addr().String()))
+
+	stopClosing := context.AfterFunc(ctx, func() {
+		_ = listener.Close()
+	})
+	defer stopClosing()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.handleConnection(ctx, conn)
+		}()
+	}
+}
+
+type errorRecord struct {
+	Error string `json:"error"`
+}
+
+func (s *Server) handleConnection(ctx context.Context, conn net.Conn) {
+	defer func() {
+		if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+			s.logger.Warn("failed to close cdc connection", zap.Error(err))
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stopClosing := context.AfterFunc(ctx, func() {
+		_ = conn.Close()
+	})
+	defer stopClosing()
+
+	writer := bufio.NewWriter(conn)
+	encoder := json.NewEncoder(writer)
+
+	from, user, err := s.handshake(conn)
+	if err != nil {
+		s.logger.Warn("invalid cdc subscription", zap.String("remote_addr", conn.RemoteAddr().String()), zap.Error(err))
+		_ = encoder.Encode(errorRecord{Error: err.Error()})
+		_ = writer.Flush()
+		return
+	}
+
+	// Подписчик ничего не отправляет после позиции, чтение нужно только чтобы заметить отключение
+	go func() {
+		_, _ = io.Copy(io.Discard, conn)
+		cancel()
+	}()
+
+	s.logger.Info("cdc subscriber connected",
+		zap.String("remote_addr", conn.RemoteAddr().String()),
+		zap.Stringer("from", from),
+	)
+
+	err = s.tailer.Run(ctx, from, func(records []Record) error {
+		for _, record := range records {
+			if !visible(user, record) {
+				continue
+			}
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	})
+
+	if err != nil && ctx.Err() == nil {
+		s.logger.Warn("cdc subscriber disconnected", zap.String("remote_addr", conn.RemoteAddr().String()), zap.Error(err))
+	}
+}
+
+func (s *Server) handshake(conn net.Conn) (Position, *auth.User, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return Position{}, nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	var user *auth.User
+	if s.authenticator != nil {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return Position{}, nil, err
+		}
+
+		user, err = s.authenticate(line)
+		if err != nil {
+			return Position{}, nil, err
+		}
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return Position{}, nil, err
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return Position{}, nil, err
+	}
+
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return Position{}, user, nil
+	}
+
+	position, err := ParsePosition(line)
+	return position, user, err
+}
+
+// authenticate проверяет строку "AUTH user password" и право пользователя на подписку
+func (s *Server) authenticate(line string) (*auth.User, error) {
+	tokens, err := compute.Tokenize(line)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) != 3 || !strings.EqualFold(tokens[0], compute.AuthCommandToken) {
+		return nil, errors.New("authentication required: send AUTH user password before the position")
+	}
+
+	user, err := s.authenticator.Authenticate(tokens[1], tokens[2])
+	if err != nil {
+		return nil, err
+	}
+	if err := user.Authorize(Permission, nil, false); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// visible проверяет, что запись доступна пользователю на чтение. user == nil - аутентификация выключена
+func visible(user *auth.User, record Record) bool {
+	if user == nil {
+		return true
+	}
+	if record.Key == "" {
+		return user.AuthorizeAll(Permission, false) == nil
+	}
+	return user.Authorize(Permission, []string{record.Key}, false) == nil
+}
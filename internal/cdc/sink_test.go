@@ -0,0 +1,237 @@
+//go:build unit
+
+package cdc_test
+
+import (
+	"bufio"
+	"concurrency_hw/internal/cdc"
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/auth"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func readRecords(t *testing.T, path string) []cdc.Record {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	require.NoError(t, err)
+
+	var records []cdc.Record
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var record cdc.Record
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestFileSink(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+	path := filepath.Join(t.TempDir(), "changes.jsonl")
+
+	runSink := func(count int) {
+		sink, err := cdc.NewFileSink(zaptest.NewLogger(t), tailer, path)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- sink.Run(ctx)
+		}()
+
+		require.Eventually(t, func() bool {
+			return len(readRecords(t, path)) >= count
+		}, 5*time.Second, 5*time.Millisecond)
+
+		cancel()
+		require.NoError(t, <-done)
+	}
+
+	for i := range 5 {
+		require.NoError(t, writer.Write([]string{fmt.Sprintf("SET key%d value", i)}))
+	}
+	runSink(5)
+
+	// После перезапуска приемник продолжает с сохраненной позиции, в том числе в новом сегменте
+	for i := 5; i < 10; i++ {
+		require.NoError(t, writer.Write([]string{fmt.Sprintf("DEL key%d", i)}))
+	}
+	runSink(10)
+
+	records := readRecords(t, path)
+	require.Len(t, records, 10)
+	for i, record := range records {
+		assert.Equal(t, fmt.Sprintf("key%d", i), record.Key)
+	}
+	assert.Equal(t, cdc.OpDel, records[9].Op)
+
+	offset, err := cdc.NewOffsetStore(path + ".offset").Load()
+	require.NoError(t, err)
+	assert.Equal(t, records[9].Next, offset)
+}
+
+// startServer поднимает CDC-сервер над WAL в dir и возвращает его адрес
+func startServer(t *testing.T, dir string, authenticator *auth.Authenticator) string {
+	t.Helper()
+
+	server, err := cdc.NewServer(zaptest.NewLogger(t), newTailer(t, dir), authenticator)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- server.Serve(ctx, listener)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		require.NoError(t, <-done)
+	})
+
+	return listener.Addr().String()
+}
+
+// subscribe подключается к CDC-серверу и отправляет строки рукопожатия
+func subscribe(t *testing.T, address string, lines ...string) (*bufio.Reader, net.Conn) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", address)
+	require.NoError(t, err)
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	for _, line := range lines {
+		_, err = conn.Write([]byte(line + "\n"))
+		require.NoError(t, err)
+	}
+
+	return bufio.NewReader(conn), conn
+}
+
+func nextRecord(t *testing.T, reader *bufio.Reader) cdc.Record {
+	t.Helper()
+
+	line, err := reader.ReadBytes('\n')
+	require.NoError(t, err)
+
+	var record cdc.Record
+	require.NoError(t, json.Unmarshal(line, &record))
+	return record
+}
+
+func TestServer(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	address := startServer(t, dir, nil)
+
+	for i := range 3 {
+		require.NoError(t, writer.Write([]string{fmt.Sprintf("SET key%d value", i)}))
+	}
+
+	reader, conn := subscribe(t, address, "")
+	first := nextRecord(t, reader)
+	assert.Equal(t, "key0", first.Key)
+	assert.Equal(t, "key1", nextRecord(t, reader).Key)
+	assert.Equal(t, "key2", nextRecord(t, reader).Key)
+
+	// Новые записи приходят без повторной подписки
+	require.NoError(t, writer.Write([]string{"DEL key0"}))
+	assert.Equal(t, cdc.OpDel, nextRecord(t, reader).Op)
+	require.NoError(t, conn.Close())
+
+	t.Run("Resume from position", func(t *testing.T) {
+		reader, conn := subscribe(t, address, first.Next.String())
+		defer conn.Close()
+
+		assert.Equal(t, "key1", nextRecord(t, reader).Key)
+	})
+
+	t.Run("Invalid position", func(t *testing.T) {
+		reader, conn := subscribe(t, address, "invalid")
+		defer conn.Close()
+
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Contains(t, line, `"error"`)
+	})
+}
+
+func TestServer_Auth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	authenticator, err := auth.NewAuthenticator(&config.AuthConfig{
+		Enabled: true,
+		Users: []config.UserConfig{
+			{Name: "replica", PasswordHash: string(hash), Commands: []string{cdc.Permission}, ReadKeys: []string{"*"}},
+			{Name: "users", PasswordHash: string(hash), Commands: []string{cdc.Permission}, ReadKeys: []string{"user:*"}},
+			{Name: "reader", PasswordHash: string(hash), Commands: []string{"GET"}, ReadKeys: []string{"*"}},
+		},
+	})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	address := startServer(t, dir, authenticator)
+
+	require.NoError(t, writer.Write([]string{"SET secret:1 value", "SET user:1 value", "FLUSHDB"}))
+
+	rejected := func(t *testing.T, lines ...string) {
+		reader, conn := subscribe(t, address, lines...)
+		defer conn.Close()
+
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Contains(t, line, `"error"`)
+	}
+
+	t.Run("Authentication required", func(t *testing.T) {
+		rejected(t, "")
+		rejected(t, "AUTH replica wrong", "")
+		rejected(t, "AUTH reader secret", "")
+	})
+
+	t.Run("All records for access to all keys", func(t *testing.T) {
+		reader, conn := subscribe(t, address, "AUTH replica secret", "")
+		defer conn.Close()
+
+		assert.Equal(t, "secret:1", nextRecord(t, reader).Key)
+		assert.Equal(t, "user:1", nextRecord(t, reader).Key)
+		assert.Equal(t, cdc.OpFlushDB, nextRecord(t, reader).Op)
+	})
+
+	t.Run("Records filtered by read keys", func(t *testing.T) {
+		reader, conn := subscribe(t, address, "AUTH users secret", "")
+		defer conn.Close()
+
+		assert.Equal(t, "user:1", nextRecord(t, reader).Key)
+
+		require.NoError(t, writer.Write([]string{"SET secret:2 value", "DEL user:1"}))
+		record := nextRecord(t, reader)
+		assert.Equal(t, cdc.OpDel, record.Op)
+		assert.Equal(t, "user:1", record.Key)
+	})
+}
@@ -0,0 +1,158 @@
+package cdc
+
+import (
+	"bufio"
+	"concurrency_hw/internal/database/storage/wal"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const maxBatchSize = 1000
+
+// Tailer читает сегменты WAL по мере того, как их дописывает StringSegmentWriter.
+// Запись считается готовой, когда в сегменте есть завершающий ее перевод строки
+type Tailer struct {
+	dir          string
+	pollInterval time.Duration
+	parser       QueryParser
+}
+
+func NewTailer(dir string, pollInterval time.Duration, parser QueryParser) (*Tailer, error) {
+	if pollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if parser == nil {
+		return nil, errors.New("parser cannot be nil")
+	}
+
+	return &Tailer{
+		dir:          dir,
+		pollInterval: pollInterval,
+		parser:       parser,
+	}, nil
+}
+
+// Run передает в emit пачки записей начиная с позиции from, пока не отменен контекст.
+// Пачки идут в порядке записи, ошибка emit останавливает чтение
+func (t *Tailer) Run(ctx context.Context, from Position, emit func([]Record) error) error {
+	position := from
+
+	ticker := time.NewTicker(t.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		next, err := t.poll(position, emit)
+		if err != nil {
+			return err
+		}
+
+		if next == position {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+
+		position = next
+	}
+}
+
+// poll читает доступные записи и возвращает позицию, с которой продолжать
+func (t *Tailer) poll(position Position, emit func([]Record) error) (Position, error) {
+	// Список сегментов берется до чтения: если следующий сегмент уже создан, текущий дописан целиком
+	segments, err := wal.ListSegments(t.dir)
+	if err != nil {
+		return position, err
+	}
+
+	current, next := -1, -1
+	for i, segment := range segments {
+		if segment.Num == position.Segment {
+			current = i
+		}
+		if segment.Num > position.Segment {
+			next = i
+			break
+		}
+	}
+
+	if current < 0 {
+		if next < 0 {
+			return position, nil
+		}
+		return Position{Segment: segments[next].Num}, nil
+	}
+
+	position, complete, err := t.read(segments[current].Path, position, emit)
+	if err != nil {
+		return position, err
+	}
+
+	if next >= 0 && complete {
+		return Position{Segment: segments[next].Num}, nil
+	}
+
+	return position, nil
+}
+
+// read читает завершенные записи сегмента. complete == true, если сегмент прочитан до конца без остатка
+func (t *Tailer) read(path string, position Position, emit func([]Record) error) (Position, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return position, false, err
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(position.Offset, io.SeekStart); err != nil {
+		return position, false, err
+	}
+
+	reader := bufio.NewReader(file)
+	batch := make([]Record, 0, maxBatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := emit(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if errors.Is(err, io.EOF) {
+			if err := flush(); err != nil {
+				return position, false, err
+			}
+			return position, line == "", nil
+		}
+		if err != nil {
+			return position, false, err
+		}
+
+		next := Position{Segment: position.Segment, Offset: position.Offset + int64(len(line))}
+
+		records, err := newRecords(t.parser, line[:len(line)-1], position, next)
+		if err != nil {
+			return position, false, fmt.Errorf("%s: %w", path, err)
+		}
+
+		batch = append(batch, records...)
+
+		if len(batch) >= maxBatchSize {
+			if err := flush(); err != nil {
+				return position, false, err
+			}
+		}
+
+		position = next
+	}
+}
@@ -0,0 +1,266 @@
+//go:build unit
+
+package cdc_test
+
+import (
+	"concurrency_hw/internal/cdc"
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/storage/wal"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func newWalWriter(t *testing.T, dir string) *wal.StringSegmentWriter {
+	t.Helper()
+
+	conf := &config.WalConfig{DataDirectory: dir, MaxSegmentSize: "64b"}
+
+	_, segment, err := wal.NewStringSegmentReader(conf)
+	require.NoError(t, err)
+
+	writer, err := wal.NewStringSegmentWriter(conf, segment)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = writer.Close()
+	})
+
+	return writer
+}
+
+func newTailer(t *testing.T, dir string) *cdc.Tailer {
+	t.Helper()
+
+	parser, err := compute.NewQueryParser(zaptest.NewLogger(t))
+	require.NoError(t, err)
+
+	tailer, err := cdc.NewTailer(dir, 5*time.Millisecond, parser)
+	require.NoError(t, err)
+
+	return tailer
+}
+
+// collect читает изменения, пока не получит count записей
+func collect(t *testing.T, tailer *cdc.Tailer, from cdc.Position, count int) []cdc.Record {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var records []cdc.Record
+	err := tailer.Run(ctx, from, func(batch []cdc.Record) error {
+		records = append(records, batch...)
+		if len(records) >= count {
+			cancel()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, records, count)
+
+	return records
+}
+
+func TestTailer_MultiKey(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	require.NoError(t, writer.Write([]string{"MSET a 1 b 2", "MDEL a b"}))
+
+	records := collect(t, tailer, cdc.Position{}, 4)
+
+	assert.Equal(t, []string{cdc.OpSet, cdc.OpSet, cdc.OpDel, cdc.OpDel},
+		[]string{records[0].Op, records[1].Op, records[2].Op, records[3].Op})
+	assert.Equal(t, "b", records[1].Key)
+	require.NotNil(t, records[1].Value)
+	assert.Equal(t, "2", *records[1].Value)
+
+	// Продолжение с Next первой записи MSET перечитывает команду целиком
+	assert.Equal(t, records[0].LSN, records[0].Next)
+	assert.Equal(t, records[0].LSN, records[1].LSN)
+	assert.Equal(t, records[2].LSN, records[1].Next)
+	assert.Equal(t, records[2].LSN, records[3].LSN)
+}
+
+func TestTailer_Hash(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	require.NoError(t, writer.Write([]string{"HSET user:1 name bob age 30", "HDEL user:1 age"}))
+
+	records := collect(t, tailer, cdc.Position{}, 3)
+
+	assert.Equal(t, []string{cdc.OpHSet, cdc.OpHSet, cdc.OpHDel},
+		[]string{records[0].Op, records[1].Op, records[2].Op})
+	assert.Equal(t, "user:1", records[1].Key)
+	assert.Equal(t, "age", records[1].Field)
+	require.NotNil(t, records[1].Value)
+	assert.Equal(t, "30", *records[1].Value)
+	assert.Equal(t, cdc.Record{LSN: records[2].LSN, Next: records[2].Next, Op: cdc.OpHDel, Key: "user:1", Field: "age"}, records[2])
+}
+
+func TestTailer_List(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	require.NoError(t, writer.Write([]string{"RPUSH jobs a b", "LPOP jobs"}))
+
+	records := collect(t, tailer, cdc.Position{}, 3)
+
+	assert.Equal(t, []string{cdc.OpRPush, cdc.OpRPush, cdc.OpLPop},
+		[]string{records[0].Op, records[1].Op, records[2].Op})
+	require.NotNil(t, records[1].Value)
+	assert.Equal(t, "b", *records[1].Value)
+	assert.Equal(t, "jobs", records[2].Key)
+	assert.Nil(t, records[2].Value)
+}
+
+func TestTailer_Sets(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	require.NoError(t, writer.Write([]string{"SADD tags go", "ZADD board 1.5 alice"}))
+
+	records := collect(t, tailer, cdc.Position{}, 2)
+
+	assert.Equal(t, cdc.Record{LSN: records[0].LSN, Next: records[0].Next, Op: cdc.OpSAdd, Key: "tags", Field: "go"}, records[0])
+	assert.Equal(t, cdc.OpZAdd, records[1].Op)
+	assert.Equal(t, "alice", records[1].Field)
+	require.NotNil(t, records[1].Value)
+	assert.Equal(t, "1.5", *records[1].Value)
+}
+
+func TestTailer_Databases(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	require.NoError(t, writer.Write([]string{"@3 SET key value", "@3 FLUSHDB", "SWAPDB 1 2", "FLUSHALL"}))
+
+	records := collect(t, tailer, cdc.Position{}, 4)
+
+	assert.Equal(t, 3, records[0].DB)
+	assert.Equal(t, cdc.OpSet, records[0].Op)
+	assert.Equal(t, cdc.Record{LSN: records[1].LSN, Next: records[1].Next, DB: 3, Op: cdc.OpFlushDB}, records[1])
+	assert.Equal(t, 1, records[2].DB)
+	assert.Equal(t, cdc.OpSwapDB, records[2].Op)
+	require.NotNil(t, records[2].Value)
+	assert.Equal(t, "2", *records[2].Value)
+	assert.Equal(t, cdc.Record{LSN: records[3].LSN, Next: records[3].Next, Op: cdc.OpFlushAll}, records[3])
+}
+
+func TestTailer_CustomCommands(t *testing.T) {
+	_, err := compute.RegisterCommand(compute.Spec{Name: "CDCAPPEND", Arity: compute.Exactly(2)})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	// Команды приложения пропускаются, зарегистрированы они в процессе CDC или нет
+	require.NoError(t, writer.Write([]string{"CDCAPPEND key tail", "CDCUNKNOWN key", "SET key value"}))
+
+	records := collect(t, tailer, cdc.Position{}, 1)
+
+	assert.Equal(t, cdc.OpSet, records[0].Op)
+	assert.Equal(t, "key", records[0].Key)
+}
+
+func TestTailer(t *testing.T) {
+	dir := t.TempDir()
+	writer := newWalWriter(t, dir)
+	tailer := newTailer(t, dir)
+
+	queries := make([]string, 20)
+	for i := range queries {
+		queries[i] = fmt.Sprintf("SET key%d value%d", i, i)
+	}
+	queries = append(queries, "DEL key0")
+
+	// Запись идет параллельно с чтением и переходит через несколько сегментов
+	go func() {
+		for _, query := range queries {
+			assert.NoError(t, writer.Write([]string{query}))
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	records := collect(t, tailer, cdc.Position{}, len(queries))
+
+	segments, err := wal.ListSegments(dir)
+	require.NoError(t, err)
+	require.Greater(t, len(segments), 1)
+
+	for i := range 20 {
+		assert.Equal(t, cdc.OpSet, records[i].Op)
+		assert.Equal(t, fmt.Sprintf("key%d", i), records[i].Key)
+		require.NotNil(t, records[i].Value)
+		assert.Equal(t, fmt.Sprintf("value%d", i), *records[i].Value)
+	}
+	assert.Equal(t, cdc.Record{LSN: records[20].LSN, Next: records[20].Next, Op: cdc.OpDel, Key: "key0"}, records[20])
+
+	t.Run("Positions are ordered", func(t *testing.T) {
+		for i := 1; i < len(records); i++ {
+			previous, current := records[i-1], records[i]
+			if current.LSN.Segment == previous.LSN.Segment {
+				assert.Equal(t, previous.Next, current.LSN)
+			} else {
+				assert.Greater(t, current.LSN.Segment, previous.LSN.Segment)
+				assert.Equal(t, int64(0), current.LSN.Offset)
+			}
+		}
+	})
+
+	t.Run("Resume after segment rotation", func(t *testing.T) {
+		for _, i := range []int{0, 5, 13} {
+			resumed := collect(t, tailer, records[i].Next, len(records)-i-1)
+			assert.Equal(t, records[i+1:], resumed)
+		}
+	})
+}
+
+func TestTailer_IncompleteRecord(t *testing.T) {
+	dir := t.TempDir()
+	tailer := newTailer(t, dir)
+
+	path := filepath.Join(dir, "0")
+	require.NoError(t, os.WriteFile(path, []byte("SET a 1\nSET b"), 0644))
+
+	records := collect(t, tailer, cdc.Position{}, 1)
+	assert.Equal(t, "a", records[0].Key)
+
+	// Запись без перевода строки еще не дописана и не читается, пока не завершится
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	require.NoError(t, err)
+	_, err = file.WriteString(" 2\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	records = collect(t, tailer, records[0].Next, 1)
+	assert.Equal(t, "b", records[0].Key)
+	assert.Equal(t, "2", *records[0].Value)
+}
+
+func TestParsePosition(t *testing.T) {
+	position, err := cdc.ParsePosition("3/120")
+	require.NoError(t, err)
+	assert.Equal(t, cdc.Position{Segment: 3, Offset: 120}, position)
+	assert.Equal(t, "3/120", position.String())
+
+	for _, malformed := range []string{"", "3", "a/1", "1/b", "-1/0", "1/-5"} {
+		_, err := cdc.ParsePosition(malformed)
+		assert.Error(t, err, malformed)
+	}
+}
@@ -16,18 +16,58 @@ type AppConfig struct {
 	NetworkConfig *NetworkConfig `yaml:"network"`
 	LoggingConfig *LoggingConfig `yaml:"logging"`
 	WalConfig     *WalConfig     `yaml:"wal"`
+	AuthConfig    *AuthConfig    `yaml:"auth"`
+	PubSubConfig  *PubSubConfig  `yaml:"pubsub"`
+	CDCConfig     *CDCConfig     `yaml:"cdc"`
+	SlowLogConfig *SlowLogConfig `yaml:"slowlog"`
+	MetricsConfig *MetricsConfig `yaml:"metrics"`
 }
 
+// EngineConfig - Type: in_memory или skiplist (упорядоченный, с RANGE и PREFIX).
+// Databases - число пронумерованных баз для SELECT, у каждой свой движок
 type EngineConfig struct {
 	Type      string `yaml:"type" env-default:"in_memory"`
 	StartSize int    `yaml:"start_size" env-default:"1000"`
+	Databases int    `yaml:"databases" env-default:"16"`
 }
 
 type NetworkConfig struct {
 	Address        string        `yaml:"address" env-default:"127.0.0.1:3223"`
 	MaxConnections int           `yaml:"max_connections" env-default:"100"`
 	MaxMessageSize string        `yaml:"max_message_size" env-default:"4KB"`
+	OversizePolicy string        `yaml:"oversize_policy" env-default:"discard"` // discard или close
 	IdleTimeout    time.Duration `yaml:"idle_timeout" env-default:"5m"`
+	// ReadTimeout ограничивает получение запроса после его первого байта, WriteTimeout - отправку ответов,
+	// HandlerTimeout - выполнение запроса, включая ожидание BLPOP. Нулевое значение выключает таймаут
+	ReadTimeout    time.Duration   `yaml:"read_timeout" env-default:"10s"`
+	WriteTimeout   time.Duration   `yaml:"write_timeout" env-default:"10s"`
+	HandlerTimeout time.Duration   `yaml:"handler_timeout" env-default:"0"`
+	KeepAlive      KeepAliveConfig `yaml:"keepalive"`
+	// Пустой адрес выключает http-шлюз
+	HTTPAddress string          `yaml:"http_address" env-default:""`
+	RateLimit   RateLimitConfig `yaml:"rate_limit"`
+}
+
+// KeepAliveConfig - TCP keepalive: первая проба после idle простоя, далее каждые interval,
+// соединение разрывается после count неотвеченных проб
+type KeepAliveConfig struct {
+	Enabled  bool          `yaml:"enabled" env-default:"true"`
+	Idle     time.Duration `yaml:"idle" env-default:"15s"`
+	Interval time.Duration `yaml:"interval" env-default:"15s"`
+	Count    int           `yaml:"count" env-default:"9"`
+}
+
+// RateLimitConfig - token bucket лимиты в запросах в секунду, нулевой rate выключает соответствующий лимит.
+// В режиме delay запрос ждет токен не дольше max_delay, в режиме reject отклоняется сразу
+type RateLimitConfig struct {
+	Mode            string        `yaml:"mode" env-default:"reject"`
+	MaxDelay        time.Duration `yaml:"max_delay" env-default:"1s"`
+	ConnectionRate  float64       `yaml:"connection_rate" env-default:"0"`
+	ConnectionBurst int           `yaml:"connection_burst" env-default:"0"`
+	UserRate        float64       `yaml:"user_rate" env-default:"0"`
+	UserBurst       int           `yaml:"user_burst" env-default:"0"`
+	GlobalRate      float64       `yaml:"global_rate" env-default:"0"`
+	GlobalBurst     int           `yaml:"global_burst" env-default:"0"`
 }
 
 type LoggingConfig struct {
@@ -35,6 +75,51 @@ type LoggingConfig struct {
 	Output string `yaml:"output" env-default:"/wal/output.wal"`
 }
 
+type AuthConfig struct {
+	Enabled bool         `yaml:"enabled" env-default:"false"`
+	Users   []UserConfig `yaml:"users"`
+}
+
+// UserConfig - пользователь и его права. Пароль хранится в виде bcrypt ($2a$...) или argon2id ($argon2id$...) хеша,
+// в commands перечисляются разрешенные команды ("*" - все), в read_keys и write_keys - glob-шаблоны ключей
+type UserConfig struct {
+	Name         string   `yaml:"name"`
+	PasswordHash string   `yaml:"password_hash"`
+	Commands     []string `yaml:"commands"`
+	ReadKeys     []string `yaml:"read_keys"`
+	WriteKeys    []string `yaml:"write_keys"`
+}
+
+// PubSubConfig - subscriber_buffer_size задает, сколько сообщений может ждать отправки подписчику.
+// При переполнении буфера подписчик отключается, а монитор MONITOR теряет строки сверх monitor_buffer_size
+type PubSubConfig struct {
+	SubscriberBufferSize int `yaml:"subscriber_buffer_size" env-default:"128"`
+	MonitorBufferSize    int `yaml:"monitor_buffer_size" env-default:"1024"`
+}
+
+// CDCConfig - выгрузка изменений из WAL. Пустые file_path и address выключают соответствующий приемник,
+// позиция файлового приемника хранится в file_path + ".offset". Подписчики address проходят AUTH, только если
+// включена аутентификация (auth.enabled), иначе изменения получает любой, кто подключился к адресу
+type CDCConfig struct {
+	Enabled      bool          `yaml:"enabled" env-default:"false"`
+	PollInterval time.Duration `yaml:"poll_interval" env-default:"100ms"`
+	FilePath     string        `yaml:"file_path" env-default:""`
+	Address      string        `yaml:"address" env-default:""`
+}
+
+// SlowLogConfig - в журнал попадают запросы не быстрее threshold, хранятся последние max_len.
+// log дублирует медленные запросы в лог сервера
+type SlowLogConfig struct {
+	Threshold time.Duration `yaml:"threshold" env-default:"10ms"`
+	MaxLen    int           `yaml:"max_len" env-default:"128"`
+	Log       bool          `yaml:"log" env-default:"false"`
+}
+
+// MetricsConfig - адрес, на котором GET /metrics отдает метрики Prometheus. Пустой адрес выключает метрики
+type MetricsConfig struct {
+	Address string `yaml:"address" env-default:""`
+}
+
 type WalConfig struct {
 	FlushingBatchSize     int           `yaml:"flushing_batch_size" env-default:"100"`
 	FlushingBatchTimeout  time.Duration `yaml:"flushing_batch_timeout" env-default:"10ms"`
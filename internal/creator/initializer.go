@@ -1,24 +1,58 @@
 package creator
 
 import (
+	"concurrency_hw/internal/cdc"
 	"concurrency_hw/internal/config"
 	"concurrency_hw/internal/database"
+	"concurrency_hw/internal/database/auth"
 	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
 	"concurrency_hw/internal/database/storage/engine/mem"
+	"concurrency_hw/internal/database/storage/engine/skiplist"
 	"concurrency_hw/internal/database/storage/wal"
+	"concurrency_hw/internal/metrics"
+	"fmt"
 	"go.uber.org/zap"
+	"time"
+)
+
+// Типы движка для engine.type. skiplist хранит ключи по порядку и поддерживает RANGE и PREFIX
+const (
+	EngineTypeInMemory = "in_memory"
+	EngineTypeSkipList = "skiplist"
 )
 
 type Creator struct {
-	logger *zap.Logger
-	conf   *config.AppConfig
+	logger  *zap.Logger
+	conf    *config.AppConfig
+	clients *network.ClientRegistry
+	// metrics == nil, если метрики выключены
+	metrics *metrics.Registry
 }
 
 func NewCreator(logger *zap.Logger, conf *config.AppConfig) *Creator {
-	return &Creator{
-		logger: logger,
-		conf:   conf,
+	creator := &Creator{
+		logger:  logger,
+		conf:    conf,
+		clients: network.NewClientRegistry(),
+	}
+
+	if conf.MetricsConfig != nil && conf.MetricsConfig.Address != "" {
+		creator.metrics = metrics.NewRegistry()
 	}
+
+	return creator
+}
+
+// Metrics - реестр метрик, в который создаваемые компоненты регистрируют свои метрики. nil, если метрики выключены
+func (i *Creator) Metrics() *metrics.Registry {
+	return i.metrics
+}
+
+// ClientRegistry - реестр соединений, общий для TCPServer и админских команд БД
+func (i *Creator) ClientRegistry() *network.ClientRegistry {
+	return i.clients
 }
 
 func (i *Creator) CreateWal() (wal.Wal, error) {
@@ -37,21 +71,116 @@ func (i *Creator) CreateWal() (wal.Wal, error) {
 		return nil, err
 	}
 
+	if i.metrics != nil {
+		walInstance.SetObserver(metrics.NewWalMetrics(i.metrics))
+	}
+
 	return walInstance, nil
 }
 
+func (i *Creator) CreateAuthenticator() (*auth.Authenticator, error) {
+	if i.conf.AuthConfig == nil || !i.conf.AuthConfig.Enabled {
+		return nil, nil
+	}
+
+	return auth.NewAuthenticator(i.conf.AuthConfig)
+}
+
+func (i *Creator) CreateCDCTailer() (*cdc.Tailer, error) {
+	parser, err := compute.NewQueryParser(i.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return cdc.NewTailer(i.conf.WalConfig.DataDirectory, i.conf.CDCConfig.PollInterval, parser)
+}
+
+func (i *Creator) CreateSlowLog() *database.SlowLog {
+	conf := i.conf.SlowLogConfig
+	if conf == nil {
+		conf = &config.SlowLogConfig{Threshold: 10 * time.Millisecond, MaxLen: 128}
+	}
+
+	var logger *zap.Logger
+	if conf.Log {
+		logger = i.logger
+	}
+
+	return database.NewSlowLog(conf.Threshold, conf.MaxLen, logger)
+}
+
+func (i *Creator) CreateEngine() (engine.Engine, error) {
+	switch i.conf.EngineConfig.Type {
+	case EngineTypeInMemory:
+		return mem.NewInMemoryEngine(i.conf.EngineConfig.StartSize), nil
+	case EngineTypeSkipList:
+		return skiplist.NewSkipListEngine(), nil
+	default:
+		return nil, fmt.Errorf("unknown engine type: %s", i.conf.EngineConfig.Type)
+	}
+}
+
+// CreateEngines создает по движку на каждую пронумерованную базу
+func (i *Creator) CreateEngines() ([]engine.Engine, error) {
+	count := i.conf.EngineConfig.Databases
+	if count < 1 {
+		return nil, fmt.Errorf("databases must be positive, got %d", count)
+	}
+
+	engines := make([]engine.Engine, 0, count)
+	for range count {
+		engine, err := i.CreateEngine()
+		if err != nil {
+			return nil, err
+		}
+		engines = append(engines, engine)
+	}
+
+	return engines, nil
+}
+
 func (i *Creator) CreateDatabase() (*database.Database, error) {
 	parser, err := compute.NewQueryParser(i.logger)
 	if err != nil {
 		i.logger.Fatal("Failed to create query parser", zap.Error(err))
 	}
 
-	engine := mem.NewInMemoryEngine(i.conf.EngineConfig.StartSize)
+	engines, err := i.CreateEngines()
+	if err != nil {
+		i.logger.Fatal("Failed to create engine", zap.Error(err))
+	}
 
 	walInstance, err := i.CreateWal()
 	if err != nil {
 		i.logger.Fatal("Failed to create wal", zap.Error(err))
 	}
 
-	return database.NewDatabase(parser, engine, walInstance)
+	authenticator, err := i.CreateAuthenticator()
+	if err != nil {
+		i.logger.Fatal("Failed to create authenticator", zap.Error(err))
+	}
+
+	bufferSize, monitorBufferSize := 0, 0
+	if i.conf.PubSubConfig != nil {
+		bufferSize = i.conf.PubSubConfig.SubscriberBufferSize
+		monitorBufferSize = i.conf.PubSubConfig.MonitorBufferSize
+	}
+	broker := database.NewBroker(bufferSize)
+	monitors := database.NewMonitors(monitorBufferSize)
+
+	var observer database.Observer
+	if i.metrics != nil {
+		observer = metrics.NewDatabaseMetrics(i.metrics)
+	}
+
+	db, err := database.NewDatabase(parser, engines, walInstance, authenticator, broker, i.clients, i.CreateSlowLog(), monitors, observer)
+	if err != nil {
+		return nil, err
+	}
+
+	if i.metrics != nil {
+		metrics.RegisterStorage(i.metrics, db, walInstance)
+	}
+
+	return db, nil
 }
@@ -0,0 +1,163 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientList - по строке на соединение: id, адрес, возраст и время простоя в секундах, последняя команда
+func (d *Database) clientList() (network.Response, error) {
+	now := time.Now()
+
+	var builder strings.Builder
+	for i, info := range d.clients.List() {
+		if i > 0 {
+			builder.WriteByte('\n')
+		}
+		fmt.Fprintf(&builder, "id=%d addr=%s age=%d idle=%d cmd=%s",
+			info.ID,
+			info.RemoteAddr,
+			int64(now.Sub(info.CreatedAt).Seconds()),
+			int64(now.Sub(info.LastActivity).Seconds()),
+			info.LastCommand,
+		)
+	}
+
+	return network.ValueResponse(builder.String()), nil
+}
+
+func (d *Database) clientKill(idArg string) (network.Response, error) {
+	id, err := strconv.ParseUint(idArg, 10, 64)
+	if err != nil {
+		err = errors.New("client id must be a positive integer")
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+
+	if !d.clients.Kill(id) {
+		return network.NotFoundResponse(), nil
+	}
+
+	return network.OKResponse(), nil
+}
+
+// info - состояние сервера строками "name:value" и число ключей непустых баз строками "db<N>:keys=<n>"
+func (d *keyspace) info() (network.Response, error) {
+	walStats, err := d.wal.Stats()
+	if err != nil {
+		err = fmt.Errorf("cannot collect wal stats: %w", err)
+		return network.ErrorResponse(network.StatusInternalError, err), err
+	}
+
+	blocked := 0
+	for _, engine := range d.engines {
+		blocked += engine.Waiters().Len()
+	}
+
+	lines := []string{
+		fmt.Sprintf("uptime_seconds:%d", int64(time.Since(d.startedAt).Seconds())),
+		fmt.Sprintf("connected_clients:%d", d.clients.Count()),
+		fmt.Sprintf("total_connections:%d", d.clients.Total()),
+		fmt.Sprintf("keys:%d", d.totalKeys()),
+		fmt.Sprintf("blocked_clients:%d", blocked),
+		fmt.Sprintf("wal_segments:%d", walStats.Segments),
+		fmt.Sprintf("wal_bytes:%d", walStats.Bytes),
+		fmt.Sprintf("monitors:%d", d.monitors.Count()),
+		fmt.Sprintf("monitor_dropped:%d", d.monitors.Dropped()),
+	}
+	for index, engine := range d.engines {
+		if keys := engine.Len(); keys > 0 {
+			lines = append(lines, fmt.Sprintf("db%d:keys=%d", index, keys))
+		}
+	}
+
+	return network.ValueResponse(strings.Join(lines, "\n")), nil
+}
+
+// dbSize - число ключей в базе сессии
+func (d *keyspace) dbSize() (network.Response, error) {
+	return network.ValueResponse(strconv.Itoa(d.engine.Len())), nil
+}
+
+// slowLogGet - по строке на медленный запрос, начиная с последнего
+func (d *Database) slowLogGet() (network.Response, error) {
+	entries := d.slowLog.Entries()
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, fmt.Sprintf("id=%d time=%s duration=%s parse=%s wal=%s apply=%s query=%s",
+			entry.ID,
+			entry.Time.UTC().Format(time.RFC3339Nano),
+			entry.Duration,
+			entry.Timings.Parse,
+			entry.Timings.Wal,
+			entry.Timings.Apply,
+			entry.Query,
+		))
+	}
+
+	return network.ValueResponse(strings.Join(lines, "\n")), nil
+}
+
+// commandInfo - COMMAND INFO [command ...], по строке на команду: имя, число аргументов и классификация.
+// Без аргументов - все команды, для неизвестной команды - nil. Имена из двух слов (CLIENT LIST), как и в
+// разборе запросов, проверяются раньше однословных
+func commandInfo(call *Call) (network.Response, error) {
+	commands := call.db.commands.list()
+	if args := call.Query.Args; len(args) > 0 {
+		commands = make([]Command, 0, len(args))
+		for i := 0; i < len(args); i++ {
+			if i+1 < len(args) {
+				if command, exists := call.db.commands.lookup(args[i] + " " + args[i+1]); exists {
+					commands = append(commands, command)
+					i++
+					continue
+				}
+			}
+			command, _ := call.db.commands.lookup(args[i])
+			commands = append(commands, command)
+		}
+	}
+
+	values := make([]*string, 0, len(commands))
+	for _, command := range commands {
+		if command.Handler == nil {
+			values = append(values, nil)
+			continue
+		}
+		line := fmt.Sprintf("name=%s arity=%s flags=%s", compute.Quote(command.Name), command.Arity, command.Flags)
+		values = append(values, &line)
+	}
+
+	return network.ValuesResponse(values), nil
+}
+
+// help - HELP [command], синтаксис команды или всех команд по строке на команду
+func help(call *Call) (network.Response, error) {
+	if len(call.Query.Args) > 0 {
+		command, exists := call.db.commands.lookup(strings.Join(call.Query.Args, " "))
+		if !exists {
+			return network.NotFoundResponse(), nil
+		}
+		return network.ValueResponse(usage(command)), nil
+	}
+
+	commands := call.db.commands.list()
+	lines := make([]string, 0, len(commands))
+	for _, command := range commands {
+		lines = append(lines, usage(command))
+	}
+
+	return network.ValueResponse(strings.Join(lines, "\n")), nil
+}
+
+func usage(command Command) string {
+	if command.Usage == "" {
+		return command.Name
+	}
+	return command.Name + " " + command.Usage
+}
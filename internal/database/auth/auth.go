@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/glob"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	allCommands = "*"
+	allKeys     = "*"
+
+	bcryptPrefix = "$2"
+	argon2Prefix = "$argon2id$"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrForbidden          = errors.New("permission denied")
+)
+
+type User struct {
+	name         string
+	passwordHash string
+	commands     map[string]bool
+	readKeys     []string
+	writeKeys    []string
+}
+
+func (u *User) Name() string {
+	return u.name
+}
+
+// Authorize проверяет право пользователя выполнить команду над ключами на чтение или запись
+func (u *User) Authorize(command string, keys []string, write bool) error {
+	if !u.commands[allCommands] && !u.commands[command] {
+		return fmt.Errorf("%w: command %s is not allowed for user %s", ErrForbidden, command, u.name)
+	}
+
+	patterns := u.readKeys
+	if write {
+		patterns = u.writeKeys
+	}
+
+	for _, key := range keys {
+		if !matchAny(patterns, key) {
+			return fmt.Errorf("%w: key %s is not accessible for user %s", ErrForbidden, key, u.name)
+		}
+	}
+
+	return nil
+}
+
+// AuthorizeAll проверяет право выполнить команду над всеми ключами (FLUSHDB, SWAPDB): среди шаблонов ключей
+// пользователя должен быть "*"
+func (u *User) AuthorizeAll(command string, write bool) error {
+	if err := u.Authorize(command, nil, write); err != nil {
+		return err
+	}
+
+	patterns := u.readKeys
+	if write {
+		patterns = u.writeKeys
+	}
+	if !slices.Contains(patterns, allKeys) {
+		return fmt.Errorf("%w: command %s requires access to all keys for user %s", ErrForbidden, command, u.name)
+	}
+
+	return nil
+}
+
+type Authenticator struct {
+	users map[string]*User
+}
+
+func NewAuthenticator(conf *config.AuthConfig) (*Authenticator, error) {
+	users := make(map[string]*User, len(conf.Users))
+
+	for _, userConf := range conf.Users {
+		if userConf.Name == "" {
+			return nil, errors.New("user name cannot be empty")
+		}
+		if _, exists := users[userConf.Name]; exists {
+			return nil, fmt.Errorf("duplicate user: %s", userConf.Name)
+		}
+		if !strings.HasPrefix(userConf.PasswordHash, bcryptPrefix) && !strings.HasPrefix(userConf.PasswordHash, argon2Prefix) {
+			return nil, fmt.Errorf("unsupported password hash format for user %s", userConf.Name)
+		}
+
+		commands := make(map[string]bool, len(userConf.Commands))
+		for _, command := range userConf.Commands {
+			commands[strings.ToUpper(command)] = true
+		}
+
+		users[userConf.Name] = &User{
+			name:         userConf.Name,
+			passwordHash: userConf.PasswordHash,
+			commands:     commands,
+			readKeys:     userConf.ReadKeys,
+			writeKeys:    userConf.WriteKeys,
+		}
+	}
+
+	return &Authenticator{users: users}, nil
+}
+
+func (a *Authenticator) Authenticate(name, password string) (*User, error) {
+	user, exists := a.users[name]
+	if !exists {
+		return nil, ErrInvalidCredentials
+	}
+
+	ok, err := verifyPassword(user.passwordHash, password)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	return user, nil
+}
+
+func verifyPassword(hash, password string) (bool, error) {
+	switch {
+	case strings.HasPrefix(hash, argon2Prefix):
+		return verifyArgon2(hash, password)
+	case strings.HasPrefix(hash, bcryptPrefix):
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, nil
+		}
+		return err == nil, err
+	default:
+		return false, errors.New("unsupported password hash format")
+	}
+}
+
+// verifyArgon2 проверяет хеш в PHC-формате: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func verifyArgon2(hash, password string) (bool, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return false, errors.New("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, errors.New("unsupported argon2id version")
+	}
+
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return false, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, fmt.Errorf("malformed argon2id salt: %w", err)
+	}
+
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return false, fmt.Errorf("malformed argon2id hash: %w", err)
+	}
+
+	actual := argon2.IDKey([]byte(password), salt, iterations, memory, threads, uint32(len(expected)))
+
+	return subtle.ConstantTimeCompare(expected, actual) == 1, nil
+}
+
+func matchAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if glob.Match(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,150 @@
+//go:build unit
+
+package auth_test
+
+import (
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/auth"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"testing"
+)
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("bcrypt-secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte("argon-secret"), salt, 1, 64, 1, 32)
+	argonHash := fmt.Sprintf("$argon2id$v=%d$m=64,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+
+	authenticator, err := auth.NewAuthenticator(&config.AuthConfig{
+		Enabled: true,
+		Users: []config.UserConfig{
+			{Name: "bcrypt", PasswordHash: string(bcryptHash)},
+			{Name: "argon", PasswordHash: argonHash},
+		},
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		wantErr  error
+	}{
+		{"Valid bcrypt password", "bcrypt", "bcrypt-secret", nil},
+		{"Invalid bcrypt password", "bcrypt", "wrong", auth.ErrInvalidCredentials},
+		{"Valid argon2id password", "argon", "argon-secret", nil},
+		{"Invalid argon2id password", "argon", "wrong", auth.ErrInvalidCredentials},
+		{"Unknown user", "unknown", "bcrypt-secret", auth.ErrInvalidCredentials},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := authenticator.Authenticate(tt.user, tt.password)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, user)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.user, user.Name())
+		})
+	}
+}
+
+func TestNewAuthenticator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		users []config.UserConfig
+	}{
+		{"Empty name", []config.UserConfig{{PasswordHash: "$2a$..."}}},
+		{"Plain text password", []config.UserConfig{{Name: "user", PasswordHash: "secret"}}},
+		{"Duplicate user", []config.UserConfig{
+			{Name: "user", PasswordHash: "$2a$..."},
+			{Name: "user", PasswordHash: "$2a$..."},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := auth.NewAuthenticator(&config.AuthConfig{Enabled: true, Users: tt.users})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUser_Authorize(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	authenticator, err := auth.NewAuthenticator(&config.AuthConfig{
+		Enabled: true,
+		Users: []config.UserConfig{{
+			Name:         "user",
+			PasswordHash: string(hash),
+			Commands:     []string{"get", "SET"},
+			ReadKeys:     []string{"user:*", "public:*"},
+			WriteKeys:    []string{"user:*"},
+		}},
+	})
+	require.NoError(t, err)
+
+	user, err := authenticator.Authenticate("user", "secret")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		command string
+		keys    []string
+		write   bool
+		allowed bool
+	}{
+		{"Read allowed key", "GET", []string{"public:1"}, false, true},
+		{"Write allowed key", "SET", []string{"user:1"}, true, true},
+		{"Write read-only key", "SET", []string{"public:1"}, true, false},
+		{"Read foreign key", "GET", []string{"secret:1"}, false, false},
+		{"Command not allowed", "DEL", []string{"user:1"}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := user.Authorize(tt.command, tt.keys, tt.write)
+			if tt.allowed {
+				assert.NoError(t, err)
+			} else {
+				assert.True(t, errors.Is(err, auth.ErrForbidden))
+			}
+		})
+	}
+}
+
+func TestUser_AuthorizeAll(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	authenticator, err := auth.NewAuthenticator(&config.AuthConfig{
+		Enabled: true,
+		Users: []config.UserConfig{
+			{Name: "admin", PasswordHash: string(hash), Commands: []string{"*"}, ReadKeys: []string{"*"}, WriteKeys: []string{"*"}},
+			{Name: "user", PasswordHash: string(hash), Commands: []string{"*"}, ReadKeys: []string{"*"}, WriteKeys: []string{"user:*"}},
+		},
+	})
+	require.NoError(t, err)
+
+	admin, err := authenticator.Authenticate("admin", "secret")
+	require.NoError(t, err)
+	assert.NoError(t, admin.AuthorizeAll("FLUSHALL", true))
+
+	user, err := authenticator.Authenticate("user", "secret")
+	require.NoError(t, err)
+	assert.ErrorIs(t, user.AuthorizeAll("FLUSHALL", true), auth.ErrForbidden)
+	assert.NoError(t, user.AuthorizeAll("SCAN", false))
+}
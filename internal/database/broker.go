@@ -0,0 +1,267 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/auth"
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/glob"
+	"errors"
+	"sync"
+)
+
+const defaultSubscriberBufferSize = 128
+
+var ErrSlowConsumer = errors.New("subscriber buffer overflow")
+
+// События изменения ключей, которые получают подписчики NOTIFY
+const (
+	KeyEventSet   = "set"
+	KeyEventDel   = "del"
+	KeyEventHSet  = "hset"
+	KeyEventHDel  = "hdel"
+	KeyEventLPush = "lpush"
+	KeyEventRPush = "rpush"
+	KeyEventLPop  = "lpop"
+	KeyEventRPop  = "rpop"
+	KeyEventSAdd  = "sadd"
+	KeyEventSRem  = "srem"
+	KeyEventZAdd  = "zadd"
+	KeyEventZRem  = "zrem"
+)
+
+// Broker рассылает сообщения PUBLISH подписчикам каналов и события изменения ключей подписчикам NOTIFY.
+// У каждого подписчика ограниченный буфер: публикация никогда не блокируется, а подписчик,
+// не успевающий читать, отключается
+type Broker struct {
+	mu         sync.RWMutex
+	channels   topics
+	patterns   topics
+	bufferSize int
+}
+
+// topics - подписчики по имени канала или glob-шаблону ключей
+type topics map[string]map[*Subscriber]struct{}
+
+func (t topics) add(topic string, subscriber *Subscriber) {
+	subscribers, exists := t[topic]
+	if !exists {
+		subscribers = make(map[*Subscriber]struct{})
+		t[topic] = subscribers
+	}
+
+	subscribers[subscriber] = struct{}{}
+}
+
+func (t topics) remove(topic string, subscriber *Subscriber) {
+	subscribers, exists := t[topic]
+	if !exists {
+		return
+	}
+
+	delete(subscribers, subscriber)
+	if len(subscribers) == 0 {
+		delete(t, topic)
+	}
+}
+
+func NewBroker(bufferSize int) *Broker {
+	if bufferSize <= 0 {
+		bufferSize = defaultSubscriberBufferSize
+	}
+
+	return &Broker{
+		channels:   make(topics),
+		patterns:   make(topics),
+		bufferSize: bufferSize,
+	}
+}
+
+// Subscriber - подписки одной сессии, реализует network.PushSource
+type Subscriber struct {
+	messages chan network.Response
+	done     chan struct{}
+	once     sync.Once
+	err      error
+	// user ограничивает события NOTIFY ключами, доступными ему на чтение. nil - без ограничений
+	user *auth.User
+	// channels, patterns и db защищаются мьютексом брокера
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	// db - база, события ключей которой получает подписчик. SELECT в режиме подписки недоступен,
+	// поэтому все шаблоны NOTIFY подписчика относятся к одной базе
+	db int
+}
+
+func (b *Broker) NewSubscriber(user *auth.User) *Subscriber {
+	return &Subscriber{
+		messages: make(chan network.Response, b.bufferSize),
+		done:     make(chan struct{}),
+		user:     user,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+}
+
+func (s *Subscriber) Messages() <-chan network.Response {
+	return s.messages
+}
+
+func (s *Subscriber) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err возвращает причину отключения, читать его можно после закрытия Done
+func (s *Subscriber) Err() error {
+	return s.err
+}
+
+func (s *Subscriber) close(err error) {
+	s.once.Do(func() {
+		s.err = err
+		close(s.done)
+	})
+}
+
+func (s *Subscriber) subscriptions() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+func (s *Subscriber) canRead(key string) bool {
+	return s.user == nil || s.user.Authorize(compute.NotifyCommandToken, []string{key}, false) == nil
+}
+
+// Subscribe подписывает на канал и возвращает число подписок подписчика
+func (b *Broker) Subscribe(subscriber *Subscriber, channel string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.channels.add(channel, subscriber)
+	subscriber.channels[channel] = struct{}{}
+
+	return subscriber.subscriptions()
+}
+
+// Unsubscribe отписывает от канала и возвращает число оставшихся подписок подписчика
+func (b *Broker) Unsubscribe(subscriber *Subscriber, channel string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.channels.remove(channel, subscriber)
+	delete(subscriber.channels, channel)
+
+	return subscriber.subscriptions()
+}
+
+// SubscribeKeys подписывает на события ключей базы db, подходящих под glob-шаблон
+func (b *Broker) SubscribeKeys(subscriber *Subscriber, db int, pattern string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.patterns.add(pattern, subscriber)
+	subscriber.patterns[pattern] = struct{}{}
+	subscriber.db = db
+
+	return subscriber.subscriptions()
+}
+
+func (b *Broker) UnsubscribeKeys(subscriber *Subscriber, pattern string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.patterns.remove(pattern, subscriber)
+	delete(subscriber.patterns, pattern)
+
+	return subscriber.subscriptions()
+}
+
+// Subscriptions возвращает число подписок подписчика
+func (b *Broker) Subscriptions(subscriber *Subscriber) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	return subscriber.subscriptions()
+}
+
+// Publish отправляет сообщение подписчикам канала и возвращает число получателей
+func (b *Broker) Publish(channel, payload string) int {
+	message := network.MessageResponse(channel, payload)
+
+	b.mu.RLock()
+	receivers, slow := deliver(b.channels[channel], message, nil)
+	b.mu.RUnlock()
+
+	b.disconnect(slow)
+
+	return receivers
+}
+
+// PublishKeyEvent отправляет событие изменения ключа базы db подписчикам подходящих шаблонов в этой базе.
+// Подписчик, подписанный на несколько подходящих шаблонов, получает событие один раз
+func (b *Broker) PublishKeyEvent(db int, key, event string) {
+	message := network.EventResponse(key, event)
+	delivered := make(map[*Subscriber]struct{})
+	var slow []*Subscriber
+
+	b.mu.RLock()
+	for pattern, subscribers := range b.patterns {
+		if !glob.Match(pattern, key) {
+			continue
+		}
+
+		_, patternSlow := deliver(subscribers, message, func(subscriber *Subscriber) bool {
+			if _, exists := delivered[subscriber]; exists || subscriber.db != db || !subscriber.canRead(key) {
+				return false
+			}
+			delivered[subscriber] = struct{}{}
+			return true
+		})
+		slow = append(slow, patternSlow...)
+	}
+	b.mu.RUnlock()
+
+	b.disconnect(slow)
+}
+
+// Remove снимает все подписки и отключает подписчика
+func (b *Broker) Remove(subscriber *Subscriber) {
+	b.mu.Lock()
+	for channel := range subscriber.channels {
+		b.channels.remove(channel, subscriber)
+	}
+	for pattern := range subscriber.patterns {
+		b.patterns.remove(pattern, subscriber)
+	}
+	clear(subscriber.channels)
+	clear(subscriber.patterns)
+	b.mu.Unlock()
+
+	subscriber.close(nil)
+}
+
+func (b *Broker) disconnect(slow []*Subscriber) {
+	for _, subscriber := range slow {
+		subscriber.close(ErrSlowConsumer)
+		b.Remove(subscriber)
+	}
+}
+
+// deliver отправляет сообщение без блокировки и возвращает число получателей и подписчиков с переполненным буфером
+func deliver(subscribers map[*Subscriber]struct{}, message network.Response, accept func(*Subscriber) bool) (int, []*Subscriber) {
+	var slow []*Subscriber
+	receivers := 0
+
+	for subscriber := range subscribers {
+		if accept != nil && !accept(subscriber) {
+			continue
+		}
+
+		select {
+		case subscriber.messages <- message:
+			receivers++
+		default:
+			slow = append(slow, subscriber)
+		}
+	}
+
+	return receivers, slow
+}
@@ -0,0 +1,107 @@
+//go:build unit
+
+package database_test
+
+import (
+	"concurrency_hw/internal/database"
+	"concurrency_hw/internal/database/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestBroker(t *testing.T) {
+	t.Run("Publish to subscribers of the channel", func(t *testing.T) {
+		broker := database.NewBroker(10)
+		first := broker.NewSubscriber(nil)
+		second := broker.NewSubscriber(nil)
+
+		assert.Equal(t, 1, broker.Subscribe(first, "news"))
+		assert.Equal(t, 2, broker.Subscribe(first, "alerts"))
+		assert.Equal(t, 1, broker.Subscribe(second, "news"))
+
+		assert.Equal(t, 2, broker.Publish("news", "hello"))
+		assert.Equal(t, 1, broker.Publish("alerts", "fire"))
+		assert.Equal(t, 0, broker.Publish("empty", "nobody"))
+
+		assert.Equal(t, network.MessageResponse("news", "hello"), <-first.Messages())
+		assert.Equal(t, network.MessageResponse("alerts", "fire"), <-first.Messages())
+		assert.Equal(t, network.MessageResponse("news", "hello"), <-second.Messages())
+	})
+
+	t.Run("Unsubscribe", func(t *testing.T) {
+		broker := database.NewBroker(10)
+		subscriber := broker.NewSubscriber(nil)
+
+		broker.Subscribe(subscriber, "news")
+		assert.Equal(t, 0, broker.Unsubscribe(subscriber, "news"))
+		assert.Equal(t, 0, broker.Unsubscribe(subscriber, "news"))
+		assert.Equal(t, 0, broker.Publish("news", "hello"))
+	})
+
+	t.Run("Slow consumer is disconnected", func(t *testing.T) {
+		broker := database.NewBroker(2)
+		slow := broker.NewSubscriber(nil)
+		fast := broker.NewSubscriber(nil)
+		broker.Subscribe(slow, "news")
+		broker.Subscribe(fast, "news")
+
+		assert.Equal(t, 2, broker.Publish("news", "1"))
+		<-fast.Messages()
+		assert.Equal(t, 2, broker.Publish("news", "2"))
+		<-fast.Messages()
+		assert.Equal(t, 1, broker.Publish("news", "3"))
+
+		select {
+		case <-slow.Done():
+		default:
+			t.Fatal("slow subscriber was not disconnected")
+		}
+		require.ErrorIs(t, slow.Err(), database.ErrSlowConsumer)
+		assert.Equal(t, 0, broker.Subscriptions(slow))
+
+		assert.Equal(t, 1, broker.Publish("news", "4"))
+	})
+
+	t.Run("Key events", func(t *testing.T) {
+		broker := database.NewBroker(10)
+		users := broker.NewSubscriber(nil)
+		all := broker.NewSubscriber(nil)
+
+		assert.Equal(t, 1, broker.SubscribeKeys(users, 0, "user:*"))
+		broker.SubscribeKeys(all, 0, "*")
+		broker.SubscribeKeys(all, 0, "user:?")
+
+		broker.PublishKeyEvent(0, "user:1", database.KeyEventSet)
+		broker.PublishKeyEvent(0, "order:1", database.KeyEventDel)
+
+		assert.Equal(t, network.EventResponse("user:1", database.KeyEventSet), <-users.Messages())
+		assert.Len(t, users.Messages(), 0)
+
+		// Событие, подходящее под несколько шаблонов, приходит один раз
+		assert.Equal(t, network.EventResponse("user:1", database.KeyEventSet), <-all.Messages())
+		assert.Equal(t, network.EventResponse("order:1", database.KeyEventDel), <-all.Messages())
+		assert.Len(t, all.Messages(), 0)
+
+		assert.Equal(t, 0, broker.UnsubscribeKeys(users, "user:*"))
+		broker.PublishKeyEvent(0, "user:2", database.KeyEventSet)
+		assert.Len(t, users.Messages(), 0)
+
+		// События других баз подписчику не приходят
+		assert.Equal(t, network.EventResponse("user:2", database.KeyEventSet), <-all.Messages())
+		broker.PublishKeyEvent(1, "user:1", database.KeyEventSet)
+		assert.Len(t, all.Messages(), 0)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		broker := database.NewBroker(10)
+		subscriber := broker.NewSubscriber(nil)
+		broker.Subscribe(subscriber, "news")
+
+		broker.Remove(subscriber)
+
+		<-subscriber.Done()
+		assert.NoError(t, subscriber.Err())
+		assert.Equal(t, 0, broker.Publish("news", "hello"))
+	})
+}
@@ -0,0 +1,284 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"strconv"
+)
+
+// builtinCommands - встроенные команды. Имя, число аргументов и описание берутся из синтаксиса compute
+func builtinCommands() []Command {
+	return []Command{
+		{id: compute.SetCommandId, Flags: FlagWrite, Keys: firstKey, Handler: set, Replay: set},
+		{id: compute.GetCommandId, Keys: firstKey, Handler: get},
+		{id: compute.DelCommandId, Flags: FlagWrite, Keys: firstKey, Handler: del, Replay: del},
+		{id: compute.AuthCommandId, Flags: FlagNoAuth | FlagServer, Handler: authenticate},
+		{id: compute.PingCommandId, Flags: FlagNoAuth | FlagServer, Handler: ping},
+
+		{id: compute.MGetCommandId, Keys: allKeys, Handler: mget},
+		// MSET и MDEL пишутся одной записью, поэтому после сбоя они либо применены целиком, либо не применены
+		{id: compute.MSetCommandId, Flags: FlagWrite, Keys: pairKeys, Handler: mset, Replay: mset},
+		{id: compute.MDelCommandId, Flags: FlagWrite, Keys: allKeys, Handler: mdel, Replay: mdel},
+
+		// Счетчики пишут в WAL SET с новым значением
+		{id: compute.IncrCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).incr)},
+		{id: compute.DecrCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).incr)},
+		{id: compute.IncrByCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).incr)},
+		{id: compute.DecrByCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).incr)},
+
+		// Ключи SCAN, KEYS, RANGE и PREFIX отбираются по правам сессии при обходе
+		{id: compute.ScanCommandId, Handler: scan},
+		{id: compute.KeysCommandId, Handler: keys},
+		{id: compute.ExistsCommandId, Keys: allKeys, Handler: exists},
+		{id: compute.RangeCommandId, Handler: ordered},
+		{id: compute.PrefixCommandId, Handler: ordered},
+
+		{id: compute.HSetCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).hashWrite), Replay: replay((*keyspace).updateHash)},
+		{id: compute.HGetCommandId, Keys: firstKey, Handler: read((*keyspace).hashRead)},
+		{id: compute.HDelCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).hashWrite), Replay: replay((*keyspace).updateHash)},
+		{id: compute.HGetAllCommandId, Keys: firstKey, Handler: read((*keyspace).hashRead)},
+		{id: compute.HLenCommandId, Keys: firstKey, Handler: read((*keyspace).hashRead)},
+		{id: compute.HExistsCommandId, Keys: firstKey, Handler: read((*keyspace).hashRead)},
+
+		{id: compute.LPushCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).listWrite), Replay: replay((*keyspace).pushList)},
+		{id: compute.RPushCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).listWrite), Replay: replay((*keyspace).pushList)},
+		{id: compute.LPopCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).listWrite), Replay: replayPop},
+		{id: compute.RPopCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).listWrite), Replay: replayPop},
+		{id: compute.LRangeCommandId, Keys: firstKey, Handler: read((*keyspace).listRead)},
+		{id: compute.LLenCommandId, Keys: firstKey, Handler: read((*keyspace).listRead)},
+		// BLPOP пишет в WAL LPOP
+		{id: compute.BLPopCommandId, Flags: FlagWrite, Keys: firstKey, Handler: blpop},
+
+		{id: compute.SAddCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).setWrite), Replay: replay((*keyspace).updateSet)},
+		{id: compute.SRemCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).setWrite), Replay: replay((*keyspace).updateSet)},
+		{id: compute.SMembersCommandId, Keys: firstKey, Handler: read((*keyspace).setRead)},
+		{id: compute.SIsMemberCommandId, Keys: firstKey, Handler: read((*keyspace).setRead)},
+		{id: compute.SCardCommandId, Keys: firstKey, Handler: read((*keyspace).setRead)},
+
+		{id: compute.ZAddCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).zsetWrite), Replay: replay((*keyspace).updateZSet)},
+		{id: compute.ZRemCommandId, Flags: FlagWrite, Keys: firstKey, Handler: write((*keyspace).zsetWrite), Replay: replay((*keyspace).updateZSet)},
+		{id: compute.ZScoreCommandId, Keys: firstKey, Handler: read((*keyspace).zsetRead)},
+		{id: compute.ZRangeCommandId, Keys: firstKey, Handler: read((*keyspace).zsetRead)},
+		{id: compute.ZRangeByScoreCommandId, Keys: firstKey, Handler: read((*keyspace).zsetRead)},
+
+		{id: compute.SubscribeCommandId, Flags: FlagPubSub | FlagServer, Handler: subscribe},
+		{id: compute.UnsubscribeCommandId, Flags: FlagPubSub | FlagServer, Handler: unsubscribe},
+		{id: compute.PublishCommandId, Flags: FlagServer, Handler: publish},
+		{id: compute.NotifyCommandId, Flags: FlagPubSub | FlagServer, Handler: subscribe},
+		{id: compute.UnnotifyCommandId, Flags: FlagPubSub | FlagServer, Handler: unsubscribe},
+
+		{id: compute.ClientListCommandId, Flags: FlagServer, Handler: clientList},
+		{id: compute.ClientKillCommandId, Flags: FlagServer, Handler: clientKill},
+		{id: compute.InfoCommandId, Handler: info},
+		{id: compute.DBSizeCommandId, Handler: dbSize},
+
+		{id: compute.SlowLogGetCommandId, Flags: FlagServer, Handler: slowLogGet},
+		{id: compute.SlowLogResetCommandId, Flags: FlagServer, Handler: slowLogReset},
+		{id: compute.MonitorCommandId, Flags: FlagServer, Handler: monitor},
+
+		// FLUSHDB, FLUSHALL и SWAPDB сами берут блокировку записи баз
+		{id: compute.SelectCommandId, Flags: FlagServer, Handler: selectDatabase},
+		{id: compute.FlushDBCommandId, Flags: FlagWrite | FlagServer | FlagAllKeys, Handler: rewriteDatabases, Replay: replayDatabases},
+		{id: compute.FlushAllCommandId, Flags: FlagWrite | FlagServer | FlagAllKeys, Handler: rewriteDatabases, Replay: replayDatabases},
+		{id: compute.SwapDBCommandId, Flags: FlagWrite | FlagServer | FlagAllKeys, Handler: rewriteDatabases, Replay: replayDatabases},
+
+		{id: compute.CommandInfoCommandId, Flags: FlagServer, Handler: commandInfo},
+		{id: compute.HelpCommandId, Flags: FlagServer, Handler: help},
+	}
+}
+
+func firstKey(args []string) []string {
+	return args[:1]
+}
+
+func allKeys(args []string) []string {
+	return args
+}
+
+// pairKeys - ключи аргументов "k1 v1 k2 v2"
+func pairKeys(args []string) []string {
+	keys, _ := splitPairs(args)
+	return keys
+}
+
+// write адаптирует изменяющую команду базы, которая сама пишет в WAL
+func write(handler func(*keyspace, compute.Query, *Timings) (network.Response, error)) Handler {
+	return func(call *Call) (network.Response, error) {
+		return handler(call.ks, call.Query, call.timings())
+	}
+}
+
+func read(handler func(*keyspace, compute.Query) (network.Response, error)) Handler {
+	return func(call *Call) (network.Response, error) {
+		return handler(call.ks, call.Query)
+	}
+}
+
+// replay адаптирует изменение хеша, списка или множества для восстановления из WAL: проверки типа значения
+// повторяются, но в WAL ничего не пишется
+func replay(update func(*keyspace, compute.Query, func(compute.Query) error) (int, error)) Handler {
+	return func(call *Call) (network.Response, error) {
+		if _, err := update(call.ks, call.Query, nil); err != nil {
+			return typedErrorResponse(err), err
+		}
+		return network.OKResponse(), nil
+	}
+}
+
+func replayPop(call *Call) (network.Response, error) {
+	if _, _, _, err := call.ks.popList(call.Query, nil, false); err != nil {
+		return typedErrorResponse(err), err
+	}
+	return network.OKResponse(), nil
+}
+
+// Строковые команды пишутся в WAL под блокировкой своих ключей, как счетчики: так порядок записей в WAL
+// совпадает с порядком изменений в памяти
+func set(call *Call) (network.Response, error) {
+	args := call.Query.Args
+	if err := call.Engine().Set(args[0], args[1], call.logQuery); err != nil {
+		return network.ErrorResponse(network.StatusStoreError, err), err
+	}
+	call.Notify(args[0], KeyEventSet)
+	return network.OKResponse(), nil
+}
+
+func get(call *Call) (network.Response, error) {
+	value, exists, err := call.Engine().Get(call.Query.Args[0])
+	if err != nil {
+		return network.ErrorResponse(network.StatusWrongType, err), err
+	}
+	if !exists {
+		return network.NotFoundResponse(), nil
+	}
+	return network.ValueResponse(value), nil
+}
+
+func del(call *Call) (network.Response, error) {
+	key := call.Query.Args[0]
+	if err := call.Engine().Del(key, call.logQuery); err != nil {
+		return network.ErrorResponse(network.StatusStoreError, err), err
+	}
+	call.Notify(key, KeyEventDel)
+	return network.OKResponse(), nil
+}
+
+func mget(call *Call) (network.Response, error) {
+	return network.ValuesResponse(call.Engine().MGet(call.Query.Args)), nil
+}
+
+func mset(call *Call) (network.Response, error) {
+	keys, values := splitPairs(call.Query.Args)
+	if err := call.Engine().MSet(keys, values, call.logQuery); err != nil {
+		return network.ErrorResponse(network.StatusStoreError, err), err
+	}
+	for _, key := range keys {
+		call.Notify(key, KeyEventSet)
+	}
+	return network.OKResponse(), nil
+}
+
+func mdel(call *Call) (network.Response, error) {
+	deleted, err := call.Engine().MDel(call.Query.Args, call.logQuery)
+	if err != nil {
+		return network.ErrorResponse(network.StatusStoreError, err), err
+	}
+	for _, key := range call.Query.Args {
+		call.Notify(key, KeyEventDel)
+	}
+	return network.ValueResponse(strconv.Itoa(deleted)), nil
+}
+
+func authenticate(call *Call) (network.Response, error) {
+	return call.db.auth(call.Session, call.Query.Args[0], call.Query.Args[1])
+}
+
+func ping(*Call) (network.Response, error) {
+	return network.ValueResponse("PONG"), nil
+}
+
+func scan(call *Call) (network.Response, error) {
+	return call.ks.scan(call.KeyFilter, call.Query.Args)
+}
+
+func keys(call *Call) (network.Response, error) {
+	return call.ks.keys(call.KeyFilter(call.Query.Args[0]))
+}
+
+func exists(call *Call) (network.Response, error) {
+	return call.ks.exists(call.Query.Args)
+}
+
+func ordered(call *Call) (network.Response, error) {
+	return call.ks.ordered(call.KeyFilter("*"), call.Query)
+}
+
+func blpop(call *Call) (network.Response, error) {
+	return call.ks.blpop(call.Session, call.Query, call.exec, call.release)
+}
+
+func subscribe(call *Call) (network.Response, error) {
+	d, subscriber := call.db, call.db.subscriber(call.Session)
+
+	var count int
+	if call.Query.CommandId == compute.NotifyCommandId {
+		count = d.broker.SubscribeKeys(subscriber, call.Session.DB, call.Query.Args[0])
+	} else {
+		count = d.broker.Subscribe(subscriber, call.Query.Args[0])
+	}
+	return network.ValueResponse(strconv.Itoa(count)), nil
+}
+
+func unsubscribe(call *Call) (network.Response, error) {
+	return call.db.unsubscribe(call.Session, call.Query)
+}
+
+func publish(call *Call) (network.Response, error) {
+	receivers := call.db.broker.Publish(call.Query.Args[0], call.Query.Args[1])
+	return network.ValueResponse(strconv.Itoa(receivers)), nil
+}
+
+func clientList(call *Call) (network.Response, error) {
+	return call.db.clientList()
+}
+
+func clientKill(call *Call) (network.Response, error) {
+	return call.db.clientKill(call.Query.Args[0])
+}
+
+func info(call *Call) (network.Response, error) {
+	return call.ks.info()
+}
+
+func dbSize(call *Call) (network.Response, error) {
+	return call.ks.dbSize()
+}
+
+func slowLogGet(call *Call) (network.Response, error) {
+	return call.db.slowLogGet()
+}
+
+func slowLogReset(call *Call) (network.Response, error) {
+	call.db.slowLog.Reset()
+	return network.OKResponse(), nil
+}
+
+func monitor(call *Call) (network.Response, error) {
+	call.db.monitor(call.Session)
+	return network.OKResponse(), nil
+}
+
+func selectDatabase(call *Call) (network.Response, error) {
+	return call.db.selectDatabase(call.Session, call.Query.Args[0])
+}
+
+func rewriteDatabases(call *Call) (network.Response, error) {
+	return call.db.rewriteDatabases(call.Session, call.Query, call.timings())
+}
+
+func replayDatabases(call *Call) (network.Response, error) {
+	if err := call.db.applyDatabases(call.ks.index, call.Query); err != nil {
+		return network.ErrorResponse(network.StatusInternalError, err), err
+	}
+	return network.OKResponse(), nil
+}
@@ -0,0 +1,215 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Flags - классификация команды
+type Flags uint8
+
+const (
+	// FlagWrite - команда изменяет данные: ключи проверяются по правам на запись
+	FlagWrite Flags = 1 << iota
+	// FlagNoAuth - команда выполняется без аутентификации и в режиме MONITOR (AUTH, PING)
+	FlagNoAuth
+	// FlagPubSub - команда разрешена в режиме подписки
+	FlagPubSub
+	// FlagServer - команда не работает с данными базы сессии и выполняется без блокировки баз
+	FlagServer
+	// FlagAllKeys - команда затрагивает все ключи: права проверяются как на ключи "*", а не на Keys
+	FlagAllKeys
+)
+
+var flagNames = []struct {
+	flag Flags
+	name string
+}{
+	{FlagWrite, "write"},
+	{FlagNoAuth, "noauth"},
+	{FlagPubSub, "pubsub"},
+	{FlagServer, "server"},
+	{FlagAllKeys, "allkeys"},
+}
+
+func (f Flags) String() string {
+	var names []string
+	for _, flag := range flagNames {
+		if f&flag.flag != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	if f&FlagWrite == 0 {
+		names = append([]string{"read"}, names...)
+	}
+	return strings.Join(names, ",")
+}
+
+// Handler выполняет команду
+type Handler func(call *Call) (network.Response, error)
+
+// Command - команда БД. Name и Arity задают синтаксис, Keys возвращает ключи запроса для проверки прав
+// (nil - проверяется только право на команду). Permission - имя команды в правах пользователя, которое она
+// требует (пусто - Name): так команда встраивающего приложения разрешается вместе со встроенной.
+// В WAL команда пишет сама через Call.Log под блокировкой ключа (commit движка или fn в Engine.Update): так порядок
+// записей в WAL совпадает с порядком изменений. Команда, которая пишет в WAL свой запрос, должна задать Replay:
+// он применяет запись при восстановлении
+type Command struct {
+	Name       string
+	Arity      compute.Arity
+	Usage      string
+	Flags      Flags
+	Permission string
+	Keys       func(args []string) []string
+	Handler    Handler
+	Replay     Handler
+
+	id compute.CommandId
+}
+
+// permission возвращает имя команды, право на которое проверяется при выполнении
+func (c Command) permission() string {
+	if c.Permission != "" {
+		return c.Permission
+	}
+	return c.Name
+}
+
+// Call - выполнение команды. Session == nil при восстановлении из WAL. Engine, Log и Notify недоступны командам с FlagServer
+type Call struct {
+	Session *network.Session
+	Query   compute.Query
+
+	command Command
+	db      *Database
+	// ks - база сессии, nil для команд с FlagServer
+	ks   *keyspace
+	exec *execution
+	// release отпускает базу до завершения команды (BLPOP перед ожиданием)
+	release func()
+}
+
+// Engine возвращает движок базы сессии
+func (c *Call) Engine() engine.Engine {
+	return c.ks.engine
+}
+
+// Log пишет запрос в WAL с номером базы сессии. Вызывается под блокировкой изменяемых ключей, ошибка должна
+// отменить изменение. При восстановлении из WAL ничего не пишет
+func (c *Call) Log(query compute.Query) error {
+	if c.replaying() {
+		return nil
+	}
+	return c.ks.walCommit(&c.exec.timings)(query)
+}
+
+// logQuery пишет в WAL запрос команды - commit для движка
+func (c *Call) logQuery() error {
+	return c.Log(c.Query)
+}
+
+// KeyFilter отбирает ключи по glob-шаблону и правам сессии на чтение по праву команды (Permission).
+// Если фильтровать нечего, возвращает nil
+func (c *Call) KeyFilter(pattern string) func(key string) bool {
+	return c.db.keyFilter(c.Session, c.command.permission(), pattern)
+}
+
+// Notify отправляет событие изменения ключа подписчикам NOTIFY базы сессии
+func (c *Call) Notify(key, event string) {
+	c.ks.notify(!c.replaying(), key, event)
+}
+
+func (c *Call) replaying() bool {
+	return c.Session == nil
+}
+
+func (c *Call) timings() *Timings {
+	return &c.exec.timings
+}
+
+// customCommands - команды встраивающего приложения, зарегистрированные через RegisterCommand
+var customCommands struct {
+	sync.Mutex
+	commands []Command
+}
+
+// RegisterCommand добавляет команду встраивающего приложения во все БД, создаваемые после регистрации:
+// БД восстанавливается из WAL при создании, и Replay команды должен быть известен к этому моменту
+func RegisterCommand(command Command) error {
+	if command.Handler == nil {
+		return fmt.Errorf("command %s has no handler", command.Name)
+	}
+
+	spec, err := compute.RegisterCommand(compute.Spec{Name: command.Name, Arity: command.Arity, Usage: command.Usage})
+	if err != nil {
+		return err
+	}
+	command.id = spec.Id
+	command.Name = spec.Name
+	command.Permission = strings.ToUpper(command.Permission)
+
+	customCommands.Lock()
+	defer customCommands.Unlock()
+
+	for _, registered := range customCommands.commands {
+		if registered.id == command.id {
+			return fmt.Errorf("command %s is already registered", command.Name)
+		}
+	}
+	customCommands.commands = append(customCommands.commands, command)
+	return nil
+}
+
+// commandRegistry - команды БД по номерам из синтаксиса compute. Не меняется после создания БД
+type commandRegistry struct {
+	commands map[compute.CommandId]Command
+}
+
+func newCommandRegistry() *commandRegistry {
+	builtin := builtinCommands()
+
+	customCommands.Lock()
+	custom := slices.Clone(customCommands.commands)
+	customCommands.Unlock()
+
+	r := &commandRegistry{commands: make(map[compute.CommandId]Command, len(builtin)+len(custom))}
+	for _, command := range builtin {
+		spec, _ := compute.CommandSpec(command.id)
+		command.Name, command.Arity, command.Usage = spec.Name, spec.Arity, spec.Usage
+		r.commands[command.id] = command
+	}
+	for _, command := range custom {
+		r.commands[command.id] = command
+	}
+	return r
+}
+
+func (r *commandRegistry) get(id compute.CommandId) (Command, bool) {
+	command, exists := r.commands[id]
+	return command, exists
+}
+
+func (r *commandRegistry) lookup(name string) (Command, bool) {
+	spec, exists := compute.LookupCommand(strings.ToUpper(name))
+	if !exists {
+		return Command{}, false
+	}
+	return r.get(spec.Id)
+}
+
+// list возвращает команды, упорядоченные по имени
+func (r *commandRegistry) list() []Command {
+	commands := make([]Command, 0, len(r.commands))
+	for _, command := range r.commands {
+		commands = append(commands, command)
+	}
+	slices.SortFunc(commands, func(a, b Command) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return commands
+}
@@ -2,23 +2,222 @@ package compute
 
 type CommandId int8
 
-type CommandSettings struct {
-	id       CommandId
-	argCount int
-}
-
 const (
-	SetCommandToken = "SET"
-	GetCommandToken = "GET"
-	DelCommandToken = "DEL"
+	SetCommandToken  = "SET"
+	GetCommandToken  = "GET"
+	DelCommandToken  = "DEL"
+	AuthCommandToken = "AUTH"
+	PingCommandToken = "PING"
+
+	SubscribeCommandToken   = "SUBSCRIBE"
+	UnsubscribeCommandToken = "UNSUBSCRIBE"
+	PublishCommandToken     = "PUBLISH"
+	NotifyCommandToken      = "NOTIFY"
+	UnnotifyCommandToken    = "UNNOTIFY"
+
+	ClientListCommandToken = "CLIENT LIST"
+	ClientKillCommandToken = "CLIENT KILL"
+	InfoCommandToken       = "INFO"
+	DBSizeCommandToken     = "DBSIZE"
+
+	SlowLogGetCommandToken   = "SLOWLOG GET"
+	SlowLogResetCommandToken = "SLOWLOG RESET"
+	MonitorCommandToken      = "MONITOR"
+
+	MGetCommandToken = "MGET"
+	MSetCommandToken = "MSET"
+	MDelCommandToken = "MDEL"
+
+	IncrCommandToken   = "INCR"
+	DecrCommandToken   = "DECR"
+	IncrByCommandToken = "INCRBY"
+	DecrByCommandToken = "DECRBY"
+
+	ScanCommandToken   = "SCAN"
+	KeysCommandToken   = "KEYS"
+	ExistsCommandToken = "EXISTS"
+	RangeCommandToken  = "RANGE"
+	PrefixCommandToken = "PREFIX"
+
+	HSetCommandToken    = "HSET"
+	HGetCommandToken    = "HGET"
+	HDelCommandToken    = "HDEL"
+	HGetAllCommandToken = "HGETALL"
+	HLenCommandToken    = "HLEN"
+	HExistsCommandToken = "HEXISTS"
+
+	LPushCommandToken  = "LPUSH"
+	RPushCommandToken  = "RPUSH"
+	LPopCommandToken   = "LPOP"
+	RPopCommandToken   = "RPOP"
+	LRangeCommandToken = "LRANGE"
+	LLenCommandToken   = "LLEN"
+	BLPopCommandToken  = "BLPOP"
+
+	SAddCommandToken      = "SADD"
+	SRemCommandToken      = "SREM"
+	SMembersCommandToken  = "SMEMBERS"
+	SIsMemberCommandToken = "SISMEMBER"
+	SCardCommandToken     = "SCARD"
+
+	ZAddCommandToken          = "ZADD"
+	ZRemCommandToken          = "ZREM"
+	ZScoreCommandToken        = "ZSCORE"
+	ZRangeCommandToken        = "ZRANGE"
+	ZRangeByScoreCommandToken = "ZRANGEBYSCORE"
+
+	SelectCommandToken   = "SELECT"
+	FlushDBCommandToken  = "FLUSHDB"
+	FlushAllCommandToken = "FLUSHALL"
+	SwapDBCommandToken   = "SWAPDB"
+
+	CommandInfoCommandToken = "COMMAND INFO"
+	HelpCommandToken        = "HELP"
+
+	SetCommandId  = CommandId(1)
+	GetCommandId  = CommandId(2)
+	DelCommandId  = CommandId(3)
+	AuthCommandId = CommandId(4)
+	PingCommandId = CommandId(5)
+
+	SubscribeCommandId   = CommandId(6)
+	UnsubscribeCommandId = CommandId(7)
+	PublishCommandId     = CommandId(8)
+	NotifyCommandId      = CommandId(9)
+	UnnotifyCommandId    = CommandId(10)
+
+	ClientListCommandId = CommandId(11)
+	ClientKillCommandId = CommandId(12)
+	InfoCommandId       = CommandId(13)
+	DBSizeCommandId     = CommandId(14)
+
+	SlowLogGetCommandId   = CommandId(15)
+	SlowLogResetCommandId = CommandId(16)
+	MonitorCommandId      = CommandId(17)
+
+	MGetCommandId = CommandId(18)
+	MSetCommandId = CommandId(19)
+	MDelCommandId = CommandId(20)
 
-	SetCommandId = CommandId(1)
-	GetCommandId = CommandId(2)
-	DelCommandId = CommandId(3)
+	IncrCommandId   = CommandId(21)
+	DecrCommandId   = CommandId(22)
+	IncrByCommandId = CommandId(23)
+	DecrByCommandId = CommandId(24)
+
+	ScanCommandId   = CommandId(25)
+	KeysCommandId   = CommandId(26)
+	ExistsCommandId = CommandId(27)
+	RangeCommandId  = CommandId(28)
+	PrefixCommandId = CommandId(29)
+
+	HSetCommandId    = CommandId(30)
+	HGetCommandId    = CommandId(31)
+	HDelCommandId    = CommandId(32)
+	HGetAllCommandId = CommandId(33)
+	HLenCommandId    = CommandId(34)
+	HExistsCommandId = CommandId(35)
+
+	LPushCommandId  = CommandId(36)
+	RPushCommandId  = CommandId(37)
+	LPopCommandId   = CommandId(38)
+	RPopCommandId   = CommandId(39)
+	LRangeCommandId = CommandId(40)
+	LLenCommandId   = CommandId(41)
+	BLPopCommandId  = CommandId(42)
+
+	SAddCommandId      = CommandId(43)
+	SRemCommandId      = CommandId(44)
+	SMembersCommandId  = CommandId(45)
+	SIsMemberCommandId = CommandId(46)
+	SCardCommandId     = CommandId(47)
+
+	ZAddCommandId          = CommandId(48)
+	ZRemCommandId          = CommandId(49)
+	ZScoreCommandId        = CommandId(50)
+	ZRangeCommandId        = CommandId(51)
+	ZRangeByScoreCommandId = CommandId(52)
+
+	SelectCommandId   = CommandId(53)
+	FlushDBCommandId  = CommandId(54)
+	FlushAllCommandId = CommandId(55)
+	SwapDBCommandId   = CommandId(56)
+
+	CommandInfoCommandId = CommandId(57)
+	HelpCommandId        = CommandId(58)
 )
 
-var commandSettings = map[string]CommandSettings{
-	SetCommandToken: {id: SetCommandId, argCount: 2},
-	GetCommandToken: {id: GetCommandId, argCount: 1},
-	DelCommandToken: {id: DelCommandId, argCount: 1},
+// builtinCommands - синтаксис встроенных команд. Команды встраивающего приложения добавляются через RegisterCommand
+var builtinCommands = map[string]Spec{
+	SetCommandToken:  {Id: SetCommandId, Arity: Exactly(2), Usage: "key value"},
+	GetCommandToken:  {Id: GetCommandId, Arity: Exactly(1), Usage: "key"},
+	DelCommandToken:  {Id: DelCommandId, Arity: Exactly(1), Usage: "key"},
+	AuthCommandToken: {Id: AuthCommandId, Arity: Exactly(2), Usage: "user password"},
+	PingCommandToken: {Id: PingCommandId, Arity: Exactly(0)},
+
+	SubscribeCommandToken:   {Id: SubscribeCommandId, Arity: Exactly(1), Usage: "pattern"},
+	UnsubscribeCommandToken: {Id: UnsubscribeCommandId, Arity: Exactly(1), Usage: "pattern"},
+	PublishCommandToken:     {Id: PublishCommandId, Arity: Exactly(2), Usage: "channel message"},
+	NotifyCommandToken:      {Id: NotifyCommandId, Arity: Exactly(1), Usage: "pattern"},
+	UnnotifyCommandToken:    {Id: UnnotifyCommandId, Arity: Exactly(1), Usage: "pattern"},
+
+	ClientListCommandToken: {Id: ClientListCommandId, Arity: Exactly(0)},
+	ClientKillCommandToken: {Id: ClientKillCommandId, Arity: Exactly(1), Usage: "id"},
+	InfoCommandToken:       {Id: InfoCommandId, Arity: Exactly(0)},
+	DBSizeCommandToken:     {Id: DBSizeCommandId, Arity: Exactly(0)},
+
+	SlowLogGetCommandToken:   {Id: SlowLogGetCommandId, Arity: Exactly(0)},
+	SlowLogResetCommandToken: {Id: SlowLogResetCommandId, Arity: Exactly(0)},
+	MonitorCommandToken:      {Id: MonitorCommandId, Arity: Exactly(0)},
+
+	MGetCommandToken: {Id: MGetCommandId, Arity: AtLeast(1, 1), Usage: "key [key ...]"},
+	MSetCommandToken: {Id: MSetCommandId, Arity: AtLeast(2, 2), Usage: "key value [key value ...]"},
+	MDelCommandToken: {Id: MDelCommandId, Arity: AtLeast(1, 1), Usage: "key [key ...]"},
+
+	IncrCommandToken:   {Id: IncrCommandId, Arity: Exactly(1), Usage: "key"},
+	DecrCommandToken:   {Id: DecrCommandId, Arity: Exactly(1), Usage: "key"},
+	IncrByCommandToken: {Id: IncrByCommandId, Arity: Exactly(2), Usage: "key increment"},
+	DecrByCommandToken: {Id: DecrByCommandId, Arity: Exactly(2), Usage: "key decrement"},
+
+	// Опции SCAN, RANGE и PREFIX идут парами
+	ScanCommandToken:   {Id: ScanCommandId, Arity: AtLeast(1, 2), Usage: "cursor [MATCH pattern] [COUNT n]"},
+	KeysCommandToken:   {Id: KeysCommandId, Arity: Exactly(1), Usage: "pattern"},
+	ExistsCommandToken: {Id: ExistsCommandId, Arity: AtLeast(1, 1), Usage: "key [key ...]"},
+	RangeCommandToken:  {Id: RangeCommandId, Arity: AtLeast(2, 2), Usage: "start end [LIMIT n]"},
+	PrefixCommandToken: {Id: PrefixCommandId, Arity: AtLeast(1, 2), Usage: "prefix [LIMIT n]"},
+
+	HSetCommandToken:    {Id: HSetCommandId, Arity: AtLeast(3, 2), Usage: "key field value [field value ...]"},
+	HGetCommandToken:    {Id: HGetCommandId, Arity: Exactly(2), Usage: "key field"},
+	HDelCommandToken:    {Id: HDelCommandId, Arity: AtLeast(2, 1), Usage: "key field [field ...]"},
+	HGetAllCommandToken: {Id: HGetAllCommandId, Arity: Exactly(1), Usage: "key"},
+	HLenCommandToken:    {Id: HLenCommandId, Arity: Exactly(1), Usage: "key"},
+	HExistsCommandToken: {Id: HExistsCommandId, Arity: Exactly(2), Usage: "key field"},
+
+	LPushCommandToken:  {Id: LPushCommandId, Arity: AtLeast(2, 1), Usage: "key value [value ...]"},
+	RPushCommandToken:  {Id: RPushCommandId, Arity: AtLeast(2, 1), Usage: "key value [value ...]"},
+	LPopCommandToken:   {Id: LPopCommandId, Arity: Exactly(1), Usage: "key"},
+	RPopCommandToken:   {Id: RPopCommandId, Arity: Exactly(1), Usage: "key"},
+	LRangeCommandToken: {Id: LRangeCommandId, Arity: Exactly(3), Usage: "key start stop"},
+	LLenCommandToken:   {Id: LLenCommandId, Arity: Exactly(1), Usage: "key"},
+	BLPopCommandToken:  {Id: BLPopCommandId, Arity: Exactly(2), Usage: "key timeout"},
+
+	SAddCommandToken:      {Id: SAddCommandId, Arity: AtLeast(2, 1), Usage: "key member [member ...]"},
+	SRemCommandToken:      {Id: SRemCommandId, Arity: AtLeast(2, 1), Usage: "key member [member ...]"},
+	SMembersCommandToken:  {Id: SMembersCommandId, Arity: Exactly(1), Usage: "key"},
+	SIsMemberCommandToken: {Id: SIsMemberCommandId, Arity: Exactly(2), Usage: "key member"},
+	SCardCommandToken:     {Id: SCardCommandId, Arity: Exactly(1), Usage: "key"},
+
+	// Опции ZRANGE и ZRANGEBYSCORE разбирает сама команда
+	ZAddCommandToken:          {Id: ZAddCommandId, Arity: AtLeast(3, 2), Usage: "key score member [score member ...]"},
+	ZRemCommandToken:          {Id: ZRemCommandId, Arity: AtLeast(2, 1), Usage: "key member [member ...]"},
+	ZScoreCommandToken:        {Id: ZScoreCommandId, Arity: Exactly(2), Usage: "key member"},
+	ZRangeCommandToken:        {Id: ZRangeCommandId, Arity: AtLeast(3, 1), Usage: "key start stop [WITHSCORES]"},
+	ZRangeByScoreCommandToken: {Id: ZRangeByScoreCommandId, Arity: AtLeast(3, 1), Usage: "key min max [WITHSCORES] [LIMIT offset count]"},
+
+	SelectCommandToken:   {Id: SelectCommandId, Arity: Exactly(1), Usage: "index"},
+	FlushDBCommandToken:  {Id: FlushDBCommandId, Arity: Exactly(0)},
+	FlushAllCommandToken: {Id: FlushAllCommandId, Arity: Exactly(0)},
+	SwapDBCommandToken:   {Id: SwapDBCommandId, Arity: Exactly(2), Usage: "index1 index2"},
+
+	CommandInfoCommandToken: {Id: CommandInfoCommandId, Arity: AtLeast(0, 1), Usage: "[command ...]"},
+	HelpCommandToken:        {Id: HelpCommandId, Arity: AtLeast(0, 1), Usage: "[command]"},
 }
@@ -0,0 +1,190 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError - ошибка разбора запроса. Pos - номер байта в запросе, начиная с 1
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Tokenize разбивает запрос на токены по пробельным символам. Токен, начинающийся с кавычки, читается
+// до закрывающей кавычки и может содержать пробелы и переводы строк:
+//   - в двойных кавычках работают \" \\ \' \n \r \t \0 и \xHH для произвольных байтов;
+//   - в одинарных кавычках экранируются только \' и \\, остальное берется как есть.
+//
+// Кавычки внутри токена без кавычек - обычные символы
+func Tokenize(input string) ([]string, error) {
+	var tokens []string
+
+	for i := 0; i < len(input); {
+		if isSpace(input[i]) {
+			i++
+			continue
+		}
+
+		if input[i] != '"' && input[i] != '\'' {
+			start := i
+			for i < len(input) && !isSpace(input[i]) {
+				i++
+			}
+			tokens = append(tokens, input[start:i])
+			continue
+		}
+
+		token, next, err := lexQuoted(input, i)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+		i = next
+	}
+
+	return tokens, nil
+}
+
+// lexQuoted читает токен в кавычках, начинающийся с позиции start, и возвращает позицию после него
+func lexQuoted(input string, start int) (string, int, error) {
+	quote := input[start]
+	var builder strings.Builder
+
+	for i := start + 1; i < len(input); {
+		c := input[i]
+
+		switch {
+		case c == quote:
+			i++
+			if i < len(input) && !isSpace(input[i]) {
+				return "", 0, &SyntaxError{Pos: i + 1, Msg: "closing quote must be followed by whitespace"}
+			}
+			return builder.String(), i, nil
+		case c == '\\' && i+1 < len(input):
+			n, err := unescape(&builder, input, i, quote)
+			if err != nil {
+				return "", 0, err
+			}
+			i += n
+		default:
+			builder.WriteByte(c)
+			i++
+		}
+	}
+
+	return "", 0, &SyntaxError{Pos: start + 1, Msg: "unterminated quoted string"}
+}
+
+// unescape пишет значение escape-последовательности с позиции i и возвращает ее длину
+func unescape(builder *strings.Builder, input string, i int, quote byte) (int, error) {
+	next := input[i+1]
+
+	if quote == '\'' {
+		if next == '\'' || next == '\\' {
+			builder.WriteByte(next)
+			return 2, nil
+		}
+		builder.WriteByte('\\')
+		return 1, nil
+	}
+
+	switch next {
+	case '"', '\'', '\\':
+		builder.WriteByte(next)
+	case 'n':
+		builder.WriteByte('\n')
+	case 'r':
+		builder.WriteByte('\r')
+	case 't':
+		builder.WriteByte('\t')
+	case '0':
+		builder.WriteByte(0)
+	case 'x':
+		if i+3 >= len(input) || !isHex(input[i+2]) || !isHex(input[i+3]) {
+			return 0, &SyntaxError{Pos: i + 1, Msg: "invalid hex escape"}
+		}
+		builder.WriteByte(unhex(input[i+2])<<4 | unhex(input[i+3]))
+		return 4, nil
+	default:
+		return 0, &SyntaxError{Pos: i + 1, Msg: fmt.Sprintf("unknown escape sequence \\%c", next)}
+	}
+
+	return 2, nil
+}
+
+// Quote возвращает токен в виде, который Tokenize разберет обратно в то же значение. Токены без пробелов,
+// управляющих символов и невалидного UTF-8 остаются как есть
+func Quote(token string) string {
+	if !needsQuote(token) {
+		return token
+	}
+
+	var builder strings.Builder
+	builder.WriteByte('"')
+
+	for i := 0; i < len(token); {
+		r, size := utf8.DecodeRuneInString(token[i:])
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&builder, "\\x%02x", token[i])
+		case r == '"' || r == '\\':
+			builder.WriteByte('\\')
+			builder.WriteRune(r)
+		case r == '\n':
+			builder.WriteString(`\n`)
+		case r == '\r':
+			builder.WriteString(`\r`)
+		case r == '\t':
+			builder.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&builder, "\\x%02x", r)
+		default:
+			builder.WriteString(token[i : i+size])
+		}
+
+		i += size
+	}
+
+	builder.WriteByte('"')
+	return builder.String()
+}
+
+func needsQuote(token string) bool {
+	if token == "" || token[0] == '"' || token[0] == '\'' || !utf8.ValidString(token) {
+		return true
+	}
+
+	for i := 0; i < len(token); i++ {
+		if token[i] < 0x20 || token[i] == ' ' || token[i] == 0x7f {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c <= '9':
+		return c - '0'
+	case c >= 'a':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,112 @@
+//go:build unit
+
+package compute_test
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		tokens []string
+	}{
+		{"Plain tokens", "SET  key\tvalue", []string{"SET", "key", "value"}},
+		{"Double quotes", `SET key "hello world"`, []string{"SET", "key", "hello world"}},
+		{"Single quotes", `SET key 'say "hi"'`, []string{"SET", "key", `say "hi"`}},
+		{"Empty quoted value", `SET key ""`, []string{"SET", "key", ""}},
+		{"Newline inside quotes", "SET key \"a\nb\"", []string{"SET", "key", "a\nb"}},
+		{"Escapes", `SET key "a\"b\\c\n\t\0"`, []string{"SET", "key", "a\"b\\c\n\t\x00"}},
+		{"Hex escapes", `SET key "\xff\x00\x7F"`, []string{"SET", "key", "\xff\x00\x7f"}},
+		{"Single quote escapes", `SET key 'it\'s \n'`, []string{"SET", "key", `it's \n`}},
+		{"Quote inside plain token", `SET key it's`, []string{"SET", "key", "it's"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, err := compute.Tokenize(tt.input)
+			require.NoError(t, err)
+			assert.Equal(t, tt.tokens, tokens)
+		})
+	}
+}
+
+func TestTokenize_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+		pos   int
+	}{
+		{"Unterminated string", `SET key "value`, "unterminated quoted string at position 9", 9},
+		{"Text after closing quote", `SET key "a"b`, "closing quote must be followed by whitespace at position 12", 12},
+		{"Invalid hex escape", `SET key "\xZZ"`, "invalid hex escape at position 10", 10},
+		{"Unknown escape", `SET key "\q"`, `unknown escape sequence \q at position 10`, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := compute.Tokenize(tt.input)
+			require.EqualError(t, err, tt.err)
+
+			var syntaxErr *compute.SyntaxError
+			require.True(t, errors.As(err, &syntaxErr))
+			assert.Equal(t, tt.pos, syntaxErr.Pos)
+		})
+	}
+}
+
+func TestQuote_RoundTrip(t *testing.T) {
+	values := []string{"plain", "", "hello world", "a\nb\r\n", `"quoted"`, "'single'", `back\slash`,
+		"\x00\x01\x7f", "\xff\xfe", "привет мир", "tab\there"}
+
+	for _, value := range values {
+		quoted := compute.Quote(value)
+		assert.NotContains(t, quoted, "\n")
+
+		tokens, err := compute.Tokenize("SET " + quoted)
+		require.NoError(t, err, quoted)
+		assert.Equal(t, []string{"SET", value}, tokens, quoted)
+	}
+
+	assert.Equal(t, "plain", compute.Quote("plain"))
+	assert.Equal(t, `"hello world"`, compute.Quote("hello world"))
+}
+
+func TestQuery_String(t *testing.T) {
+	query := compute.Query{CommandId: compute.SetCommandId, Args: []string{"key", "multi\nline value"}}
+	assert.Equal(t, `SET key "multi\nline value"`, query.String())
+}
+
+func TestRecord_RoundTrip(t *testing.T) {
+	query := compute.Query{CommandId: compute.SetCommandId, Args: []string{"key", "two words"}}
+
+	assert.Equal(t, `@0 SET key "two words"`, compute.EncodeRecord(0, query))
+	assert.Equal(t, `@3 SET key "two words"`, compute.EncodeRecord(3, query))
+
+	for _, db := range []int{0, 3} {
+		index, decoded, err := compute.DecodeRecord(compute.EncodeRecord(db, query))
+		require.NoError(t, err)
+		assert.Equal(t, db, index)
+		assert.Equal(t, query.String(), decoded)
+	}
+
+	for _, malformed := range []string{"@ SET k v", "@x SET k v", "@-1 SET k v"} {
+		_, _, err := compute.DecodeRecord(malformed)
+		assert.Error(t, err, malformed)
+	}
+
+	// Записи без номера базы - старый формат: токены разделены пробелами, кавычки в них - обычные символы
+	index, decoded, err := compute.DecodeRecord(`SET k "abc`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, index)
+
+	tokens, err := compute.Tokenize(decoded)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SET", "k", `"abc`}, tokens)
+}
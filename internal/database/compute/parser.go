@@ -7,6 +7,9 @@ import (
 	"strings"
 )
 
+// ErrUnknownCommand - в запросе нет зарегистрированной команды
+var ErrUnknownCommand = errors.New("invalid command token")
+
 type QueryParser struct {
 	logger *zap.Logger
 }
@@ -21,42 +24,43 @@ func NewQueryParser(logger *zap.Logger) (*QueryParser, error) {
 func (p *QueryParser) ParseQuery(queryString string) (Query, error) {
 	p.logger.Debug("parsing query", zap.String("query", queryString))
 
-	tokens := strings.Fields(queryString)
+	tokens, err := Tokenize(queryString)
+	if err != nil {
+		p.logger.Debug("error tokenizing query", zap.String("query", queryString), zap.Error(err))
+		return Query{}, err
+	}
 
 	if len(tokens) == 0 {
 		p.logger.Debug("no tokens found", zap.String("query", queryString))
 		return Query{}, errors.New("no tokens found")
 	}
 
-	commandToken := tokens[0]
-	commandSettings, err := parseCommandSettings(commandToken)
-	if err != nil {
+	// Команды из двух слов (CLIENT LIST) проверяются раньше однословных
+	commandLength := 1
+	if len(tokens) > 1 {
+		if _, exists := LookupCommand(tokens[0] + " " + tokens[1]); exists {
+			commandLength = 2
+		}
+	}
+
+	commandToken := strings.Join(tokens[:commandLength], " ")
+	spec, exists := LookupCommand(commandToken)
+	if !exists {
+		err := fmt.Errorf("%w: %s", ErrUnknownCommand, commandToken)
 		p.logger.Debug("error parsing settings", zap.String("query", queryString), zap.Error(err))
 		return Query{}, err
 	}
 
-	query, err := mapQuery(tokens[1:], commandSettings)
-	if err != nil {
+	args := tokens[commandLength:]
+	if !spec.Arity.Valid(len(args)) {
+		err := errors.New("invalid count of arguments")
 		p.logger.Debug("error parsing query", zap.String("query", queryString), zap.Error(err))
 		return Query{}, err
 	}
-	return query, nil
+	return Query{CommandId: spec.Id, Args: args}, nil
 }
 
+// CleanQuery убирает пробельные символы по краям запроса. Переводы строк внутри кавычек сохраняются
 func (p *QueryParser) CleanQuery(queryString string) string {
-	return strings.ReplaceAll(queryString, "\n", "")
-}
-
-func parseCommandSettings(token string) (CommandSettings, error) {
-	if setting, exists := commandSettings[token]; exists {
-		return setting, nil
-	}
-	return CommandSettings{}, fmt.Errorf("invalid command token: %s", token)
-}
-
-func mapQuery(args []string, settings CommandSettings) (Query, error) {
-	if len(args) != settings.argCount {
-		return Query{}, errors.New("invalid count of arguments")
-	}
-	return Query{CommandId: settings.id, Args: args}, nil
+	return strings.TrimSpace(queryString)
 }
@@ -5,6 +5,7 @@ package compute_test
 import (
 	"concurrency_hw/internal/database/compute"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
 	"testing"
@@ -60,6 +61,87 @@ func TestQueryParser_ParseQuery(t *testing.T) {
 			wantQuery: compute.Query{CommandId: compute.DelCommandId, Args: []string{"key"}},
 			wantErr:   false,
 		},
+		{
+			name:      "Valid CLIENT KILL command",
+			query:     "CLIENT KILL 7",
+			wantQuery: compute.Query{CommandId: compute.ClientKillCommandId, Args: []string{"7"}},
+		},
+		{
+			name:      "Valid SWAPDB command",
+			query:     "SWAPDB 0 1",
+			wantQuery: compute.Query{CommandId: compute.SwapDBCommandId, Args: []string{"0", "1"}},
+		},
+		{
+			name:      "Valid FLUSHDB command",
+			query:     "FLUSHDB",
+			wantQuery: compute.Query{CommandId: compute.FlushDBCommandId},
+		},
+		{
+			name:      "Valid CLIENT LIST command",
+			query:     "CLIENT LIST",
+			wantQuery: compute.Query{CommandId: compute.ClientListCommandId},
+		},
+		{
+			name:      "SET with quoted value",
+			query:     `SET key "hello world"`,
+			wantQuery: compute.Query{CommandId: compute.SetCommandId, Args: []string{"key", "hello world"}},
+		},
+		{
+			name:    "Unterminated quoted value",
+			query:   `SET key 'hello`,
+			wantErr: true,
+			errMsg:  "unterminated quoted string at position 9",
+		},
+		{
+			name:      "MSET with several pairs",
+			query:     "MSET a 1 b 2",
+			wantQuery: compute.Query{CommandId: compute.MSetCommandId, Args: []string{"a", "1", "b", "2"}},
+		},
+		{
+			name:      "MGET with several keys",
+			query:     "MGET a b c",
+			wantQuery: compute.Query{CommandId: compute.MGetCommandId, Args: []string{"a", "b", "c"}},
+		},
+		{
+			name:      "SCAN with options",
+			query:     "SCAN 0 MATCH user:* COUNT 100",
+			wantQuery: compute.Query{CommandId: compute.ScanCommandId, Args: []string{"0", "MATCH", "user:*", "COUNT", "100"}},
+		},
+		{
+			name:    "SCAN with option without value",
+			query:   "SCAN 0 MATCH",
+			wantErr: true,
+			errMsg:  "invalid count of arguments",
+		},
+		{
+			name:      "RANGE with limit",
+			query:     "RANGE a z LIMIT 10",
+			wantQuery: compute.Query{CommandId: compute.RangeCommandId, Args: []string{"a", "z", "LIMIT", "10"}},
+		},
+		{
+			name:    "PREFIX without prefix",
+			query:   "PREFIX",
+			wantErr: true,
+			errMsg:  "invalid count of arguments",
+		},
+		{
+			name:    "MSET with incomplete pair",
+			query:   "MSET a 1 b",
+			wantErr: true,
+			errMsg:  "invalid count of arguments",
+		},
+		{
+			name:    "MDEL without keys",
+			query:   "MDEL",
+			wantErr: true,
+			errMsg:  "invalid count of arguments",
+		},
+		{
+			name:    "CLIENT without subcommand",
+			query:   "CLIENT",
+			wantErr: true,
+			errMsg:  "invalid command token: CLIENT",
+		},
 		{
 			name:    "Empty query",
 			query:   "",
@@ -112,3 +194,57 @@ func TestQueryParser_ParseQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestArity(t *testing.T) {
+	tests := []struct {
+		arity   compute.Arity
+		text    string
+		valid   []int
+		invalid []int
+	}{
+		{arity: compute.Exactly(2), text: "2", valid: []int{2}, invalid: []int{0, 1, 3}},
+		{arity: compute.Between(0, 1), text: "0..1", valid: []int{0, 1}, invalid: []int{2}},
+		{arity: compute.AtLeast(1, 1), text: "1+", valid: []int{1, 2, 5}, invalid: []int{0}},
+		{arity: compute.AtLeast(3, 2), text: "3+2", valid: []int{3, 5, 7}, invalid: []int{2, 4, 6}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.text, tt.arity.String())
+		for _, count := range tt.valid {
+			assert.True(t, tt.arity.Valid(count), "%s: %d", tt.text, count)
+		}
+		for _, count := range tt.invalid {
+			assert.False(t, tt.arity.Valid(count), "%s: %d", tt.text, count)
+		}
+	}
+}
+
+func TestRegisterCommand(t *testing.T) {
+	parser, _ := compute.NewQueryParser(zaptest.NewLogger(t))
+
+	spec, err := compute.RegisterCommand(compute.Spec{Name: "cache warm", Arity: compute.AtLeast(1, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, "CACHE WARM", spec.Name)
+	assert.Equal(t, "CACHE WARM", compute.CommandName(spec.Id))
+
+	// Повторная регистрация с тем же синтаксисом возвращает тот же номер
+	again, err := compute.RegisterCommand(compute.Spec{Name: "CACHE WARM", Arity: compute.AtLeast(1, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, spec.Id, again.Id)
+
+	query, err := parser.ParseQuery("CACHE WARM a b")
+	require.NoError(t, err)
+	assert.Equal(t, compute.Query{CommandId: spec.Id, Args: []string{"a", "b"}}, query)
+	assert.Equal(t, "CACHE WARM a b", query.String())
+
+	for _, invalid := range []compute.Spec{
+		{Name: "CACHE WARM", Arity: compute.Exactly(1)},
+		{Name: "SET", Arity: compute.Exactly(2)},
+		{Name: "", Arity: compute.Exactly(0)},
+		{Name: "ONE TWO THREE", Arity: compute.Exactly(0)},
+		{Name: "BROKEN", Arity: compute.Arity{Min: 2, Max: 1}},
+	} {
+		_, err := compute.RegisterCommand(invalid)
+		assert.Error(t, err, invalid.Name)
+	}
+}
@@ -1,6 +1,22 @@
 package compute
 
+import "strings"
+
 type Query struct {
 	CommandId CommandId
 	Args      []string
 }
+
+// String возвращает запрос в каноническом виде: аргументы при необходимости в кавычках, без переводов строк.
+// В таком виде запрос пишется в WAL и разбирается обратно в те же аргументы
+func (q Query) String() string {
+	var builder strings.Builder
+	builder.WriteString(CommandName(q.CommandId))
+
+	for _, arg := range q.Args {
+		builder.WriteByte(' ')
+		builder.WriteString(Quote(arg))
+	}
+
+	return builder.String()
+}
@@ -0,0 +1,42 @@
+package compute
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// recordDBPrefix начинает каждую запись WAL номером базы: "@3 SET k v". Запись без префикса - формат до появления
+// кавычек и нескольких баз: токены разделены пробельными символами, кавычки в них - обычные символы,
+// а запись относится к базе 0
+const recordDBPrefix = "@"
+
+// EncodeRecord возвращает запись WAL для запроса к базе db
+func EncodeRecord(db int, query Query) string {
+	return recordDBPrefix + strconv.Itoa(db) + " " + query.String()
+}
+
+// DecodeRecord отделяет номер базы от запроса в записи WAL. Запрос старого формата приводится к каноническому виду
+func DecodeRecord(record string) (int, string, error) {
+	rest, found := strings.CutPrefix(record, recordDBPrefix)
+	if !found {
+		return 0, legacyQuery(record), nil
+	}
+
+	index, query, _ := strings.Cut(rest, " ")
+	db, err := strconv.Atoi(index)
+	if err != nil || db < 0 {
+		return 0, "", fmt.Errorf("malformed database index in wal record: %s", record)
+	}
+	return db, query, nil
+}
+
+// legacyQuery разбивает запись старого формата по пробельным символам, как это делал прежний парсер,
+// и берет токены в кавычки там, где Tokenize понял бы их иначе
+func legacyQuery(record string) string {
+	tokens := strings.Fields(record)
+	for i, token := range tokens {
+		tokens[i] = Quote(token)
+	}
+	return strings.Join(tokens, " ")
+}
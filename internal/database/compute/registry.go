@@ -0,0 +1,156 @@
+package compute
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Variadic - Arity.Max команды с неограниченным числом аргументов
+const Variadic = -1
+
+// Arity - допустимое число аргументов: от Min до Max. Аргументы сверх Min идут группами по Step (MSET k1 v1 k2 v2)
+type Arity struct {
+	Min  int
+	Max  int
+	Step int
+}
+
+func Exactly(count int) Arity {
+	return Arity{Min: count, Max: count, Step: 1}
+}
+
+func AtLeast(count, step int) Arity {
+	return Arity{Min: count, Max: Variadic, Step: step}
+}
+
+func Between(min, max int) Arity {
+	return Arity{Min: min, Max: max, Step: 1}
+}
+
+func (a Arity) Valid(count int) bool {
+	if count < a.Min || a.Max != Variadic && count > a.Max {
+		return false
+	}
+	return a.Step <= 1 || (count-a.Min)%a.Step == 0
+}
+
+// String - "2" для точного числа аргументов, "1..3" для диапазона, "2+" и "1+2" (группами по 2) без ограничения
+func (a Arity) String() string {
+	switch {
+	case a.Max == a.Min:
+		return strconv.Itoa(a.Min)
+	case a.Max != Variadic:
+		return fmt.Sprintf("%d..%d", a.Min, a.Max)
+	case a.Step > 1:
+		return fmt.Sprintf("%d+%d", a.Min, a.Step)
+	default:
+		return fmt.Sprintf("%d+", a.Min)
+	}
+}
+
+func (a Arity) validate() error {
+	if a.Min < 0 || a.Max != Variadic && a.Max < a.Min || a.Step < 0 {
+		return fmt.Errorf("invalid arity: %s", a)
+	}
+	return nil
+}
+
+// Spec - синтаксис команды: имя из одного или двух слов, номер, число аргументов и их описание для HELP
+type Spec struct {
+	Id    CommandId
+	Name  string
+	Arity Arity
+	Usage string
+}
+
+// registry - синтаксис всех команд: по нему разбираются запросы и записи WAL
+type registry struct {
+	mu     sync.RWMutex
+	byName map[string]Spec
+	byId   map[CommandId]Spec
+	nextId CommandId
+	// firstCustom - номер первой команды, добавленной через RegisterCommand
+	firstCustom CommandId
+}
+
+var commands = newRegistry(builtinCommands)
+
+func newRegistry(builtin map[string]Spec) *registry {
+	r := &registry{
+		byName: make(map[string]Spec, len(builtin)),
+		byId:   make(map[CommandId]Spec, len(builtin)),
+	}
+	for name, spec := range builtin {
+		spec.Name = name
+		r.byName[name] = spec
+		r.byId[spec.Id] = spec
+		r.nextId = max(r.nextId, spec.Id+1)
+	}
+	r.firstCustom = r.nextId
+	return r
+}
+
+// RegisterCommand добавляет синтаксис команды и назначает ей номер. Повторная регистрация команды с тем же
+// синтаксисом возвращает уже назначенный номер, встроенные команды переопределить нельзя
+func RegisterCommand(spec Spec) (Spec, error) {
+	return commands.register(spec)
+}
+
+func (r *registry) register(spec Spec) (Spec, error) {
+	spec.Name = strings.ToUpper(strings.Join(strings.Fields(spec.Name), " "))
+	if spec.Name == "" || strings.Count(spec.Name, " ") > 1 {
+		return Spec{}, errors.New("command name must be one or two words")
+	}
+	if err := spec.Arity.validate(); err != nil {
+		return Spec{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, exists := r.byName[spec.Name]; exists {
+		if existing.Id < r.firstCustom {
+			return Spec{}, fmt.Errorf("command %s is built in", spec.Name)
+		}
+		if existing.Arity != spec.Arity {
+			return Spec{}, fmt.Errorf("command %s is already registered with arity %s", spec.Name, existing.Arity)
+		}
+		return existing, nil
+	}
+	if r.nextId == math.MaxInt8 {
+		return Spec{}, errors.New("too many commands registered")
+	}
+
+	spec.Id = r.nextId
+	r.nextId++
+	r.byName[spec.Name] = spec
+	r.byId[spec.Id] = spec
+	return spec, nil
+}
+
+// LookupCommand возвращает синтаксис команды по имени
+func LookupCommand(name string) (Spec, bool) {
+	commands.mu.RLock()
+	defer commands.mu.RUnlock()
+
+	spec, exists := commands.byName[name]
+	return spec, exists
+}
+
+// CommandSpec возвращает синтаксис команды по номеру
+func CommandSpec(id CommandId) (Spec, bool) {
+	commands.mu.RLock()
+	defer commands.mu.RUnlock()
+
+	spec, exists := commands.byId[id]
+	return spec, exists
+}
+
+func CommandName(id CommandId) string {
+	spec, _ := CommandSpec(id)
+	return spec.Name
+}
@@ -0,0 +1,67 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"errors"
+	"math"
+	"strconv"
+)
+
+// incr выполняет INCR, DECR, INCRBY и DECRBY. В WAL пишется SET с новым значением, а не приращение,
+// поэтому повторное применение WAL дает тот же результат
+func (d *keyspace) incr(query compute.Query, timings *Timings) (network.Response, error) {
+	key := query.Args[0]
+
+	delta, err := incrDelta(query)
+	if err != nil {
+		status := network.StatusInvalidArgument
+		if errors.Is(err, engine.ErrOverflow) {
+			status = network.StatusOverflow
+		}
+		return network.ErrorResponse(status, err), err
+	}
+
+	commit := d.walCommit(timings)
+	result, err := d.engine.IncrBy(key, delta, func(value string) error {
+		return commit(compute.Query{CommandId: compute.SetCommandId, Args: []string{key, value}})
+	})
+
+	switch {
+	case errors.Is(err, engine.ErrNotInteger):
+		return network.ErrorResponse(network.StatusNotInteger, err), err
+	case errors.Is(err, engine.ErrOverflow):
+		return network.ErrorResponse(network.StatusOverflow, err), err
+	case errors.Is(err, engine.ErrWrongType):
+		return network.ErrorResponse(network.StatusWrongType, err), err
+	case err != nil:
+		return network.ErrorResponse(network.StatusStoreError, err), err
+	}
+
+	d.notify(true, key, KeyEventSet)
+	return network.ValueResponse(strconv.FormatInt(result, 10)), nil
+}
+
+func incrDelta(query compute.Query) (int64, error) {
+	switch query.CommandId {
+	case compute.IncrCommandId:
+		return 1, nil
+	case compute.DecrCommandId:
+		return -1, nil
+	}
+
+	delta, err := strconv.ParseInt(query.Args[1], 10, 64)
+	if err != nil {
+		return 0, errors.New("increment must be an integer")
+	}
+
+	if query.CommandId == compute.DecrByCommandId {
+		if delta == math.MinInt64 {
+			return 0, engine.ErrOverflow
+		}
+		delta = -delta
+	}
+
+	return delta, nil
+}
@@ -1,11 +1,16 @@
 package database
 
 import (
+	"concurrency_hw/internal/database/auth"
 	"concurrency_hw/internal/database/compute"
 	"concurrency_hw/internal/database/network"
 	"concurrency_hw/internal/database/storage/engine"
 	"concurrency_hw/internal/database/storage/wal"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type PreProcessor interface {
@@ -13,21 +18,56 @@ type PreProcessor interface {
 	ParseQuery(queryString string) (compute.Query, error)
 }
 
+// Observer получает статистику каждого выполненного запроса. command пустой, если запрос не разобран
+type Observer interface {
+	ObserveQuery(command string, status network.StatusCode, timings Timings)
+}
+
 type Database struct {
 	preProcessor PreProcessor
-	engine       engine.Engine
-	wal          wal.Wal
+	// mu защищает engines: команды над данными держат блокировку чтения, FLUSHDB, FLUSHALL и SWAPDB - записи
+	mu            sync.RWMutex
+	engines       []engine.Engine
+	commands      *commandRegistry
+	wal           wal.Wal
+	authenticator *auth.Authenticator
+	broker        *Broker
+	clients       *network.ClientRegistry
+	slowLog       *SlowLog
+	monitors      *Monitors
+	observer      Observer
+	startedAt     time.Time
 }
 
+// NewDatabase создает БД с пронумерованными базами, по одной на движок engines, со встроенными командами
+// и командами, зарегистрированными через RegisterCommand. Если authenticator == nil, аутентификация выключена
 func NewDatabase(
 	preProcessor PreProcessor,
-	engine engine.Engine,
+	engines []engine.Engine,
 	wal wal.Wal,
+	authenticator *auth.Authenticator,
+	broker *Broker,
+	clients *network.ClientRegistry,
+	slowLog *SlowLog,
+	monitors *Monitors,
+	observer Observer,
 ) (*Database, error) {
+	if len(engines) == 0 {
+		return nil, errors.New("at least one database engine is required")
+	}
+
 	db := &Database{
-		preProcessor: preProcessor,
-		engine:       engine,
-		wal:          wal,
+		preProcessor:  preProcessor,
+		engines:       engines,
+		commands:      newCommandRegistry(),
+		wal:           wal,
+		authenticator: authenticator,
+		broker:        broker,
+		clients:       clients,
+		slowLog:       slowLog,
+		monitors:      monitors,
+		observer:      observer,
+		startedAt:     time.Now(),
 	}
 
 	err := db.Load()
@@ -39,8 +79,27 @@ func NewDatabase(
 }
 
 func (d *Database) Load() error {
-	err := d.wal.ForEach(func(queryString string) error {
-		_, err := d.executeWithWal(queryString, false)
+	err := d.wal.ForEach(func(record string) error {
+		index, queryString, err := compute.DecodeRecord(record)
+		if err != nil {
+			return err
+		}
+		if index >= len(d.engines) {
+			return fmt.Errorf("wal record for database %d, but only %d databases configured", index, len(d.engines))
+		}
+
+		query, err := d.parse(queryString)
+		if err != nil {
+			return err
+		}
+
+		command, exists := d.commands.get(query.CommandId)
+		if !exists || command.Replay == nil {
+			return fmt.Errorf("command %s cannot be replayed from wal", compute.CommandName(query.CommandId))
+		}
+
+		call := &Call{Query: query, command: command, db: d, ks: d.keyspace(index), exec: &execution{}}
+		_, err = command.Replay(call)
 		return err
 	})
 
@@ -51,40 +110,226 @@ func (d *Database) Load() error {
 	return nil
 }
 
-func (d *Database) Execute(queryString string) (string, error) {
-	return d.executeWithWal(queryString, true)
+// Execute выполняет запрос сессии, учитывает его в журнале медленных запросов, метриках и отправляет мониторам
+func (d *Database) Execute(session *network.Session, queryString string) (network.Response, error) {
+	var exec execution
+	start := time.Now()
+
+	response, err := d.execute(session, queryString, &exec)
+
+	d.slowLog.Observe(queryString, time.Since(start)-exec.blocked, exec.timings)
+	d.monitors.Feed(session.RemoteAddr, queryString, response.Status)
+	if d.observer != nil {
+		d.observer.ObserveQuery(compute.CommandName(exec.command), response.Status, exec.timings)
+	}
+
+	return response, err
+}
+
+// execution - то, что Execute узнает о запросе по ходу выполнения
+type execution struct {
+	command compute.CommandId
+	timings Timings
+	// blocked - время ожидания блокирующей команды (BLPOP)
+	blocked time.Duration
+}
+
+func (d *Database) execute(session *network.Session, queryString string, exec *execution) (network.Response, error) {
+	timings := &exec.timings
+
+	parseStart := time.Now()
+	query, err := d.parse(queryString)
+	timings.Parse = time.Since(parseStart)
+	if err != nil {
+		return network.ErrorResponse(network.StatusParseError, err), err
+	}
+	exec.command = query.CommandId
+
+	command, exists := d.commands.get(query.CommandId)
+	if !exists {
+		err := fmt.Errorf("unknown command: %s", compute.CommandName(query.CommandId))
+		return network.ErrorResponse(network.StatusUnknownCommand, err), err
+	}
+
+	if command.Flags&FlagNoAuth == 0 {
+		if response, err := d.authorize(session, command, query); err != nil {
+			return response, err
+		}
+
+		if d.monitoring(session) {
+			err := errors.New("only PING is allowed in monitor mode")
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+
+		if command.Flags&FlagPubSub == 0 && d.subscribed(session) {
+			err := errors.New("only SUBSCRIBE, UNSUBSCRIBE, NOTIFY, UNNOTIFY and PING are allowed in subscribed mode")
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+	}
+
+	call := &Call{Session: session, Query: query, command: command, db: d, exec: exec, release: func() {}}
+	if command.Flags&FlagServer == 0 {
+		ks, release := d.acquire(session.DB)
+		defer release()
+		call.ks, call.release = ks, release
+	}
+
+	// Время записи в WAL внутри команды и ожидания блокирующей команды не входит в применение
+	walBefore := timings.Wal
+	applyStart := time.Now()
+	response, err := command.Handler(call)
+	timings.Apply = time.Since(applyStart) - (timings.Wal - walBefore) - exec.blocked
+
+	return response, err
+}
+
+// subscriber возвращает подписчика сессии, создавая его при первой подписке. При закрытии сессии
+// подписчик удаляется из брокера
+func (d *Database) subscriber(session *network.Session) *Subscriber {
+	if subscriber, ok := session.PushSource().(*Subscriber); ok {
+		return subscriber
+	}
+
+	subscriber := d.broker.NewSubscriber(session.User)
+	session.SetPushSource(subscriber)
+	session.OnClose(func() {
+		d.broker.Remove(subscriber)
+	})
+
+	return subscriber
 }
 
-func (d *Database) executeWithWal(queryString string, useWal bool) (string, error) {
+func (d *Database) unsubscribe(session *network.Session, query compute.Query) (network.Response, error) {
+	count := 0
+	if subscriber, ok := session.PushSource().(*Subscriber); ok {
+		if query.CommandId == compute.UnnotifyCommandId {
+			count = d.broker.UnsubscribeKeys(subscriber, query.Args[0])
+		} else {
+			count = d.broker.Unsubscribe(subscriber, query.Args[0])
+		}
+	}
+
+	return network.ValueResponse(strconv.Itoa(count)), nil
+}
+
+func (d *Database) subscribed(session *network.Session) bool {
+	subscriber, ok := session.PushSource().(*Subscriber)
+	return ok && d.broker.Subscriptions(subscriber) > 0
+}
+
+// monitor переводит сессию в режим MONITOR до закрытия соединения
+func (d *Database) monitor(session *network.Session) {
+	monitor := d.monitors.Add()
+	session.SetPushSource(monitor)
+	session.OnClose(func() {
+		d.monitors.Remove(monitor)
+	})
+}
+
+func (d *Database) monitoring(session *network.Session) bool {
+	_, ok := session.PushSource().(*Monitor)
+	return ok
+}
+
+func (d *Database) parse(queryString string) (compute.Query, error) {
 	cleaned := d.preProcessor.CleanQuery(queryString)
 
-	query, err := d.preProcessor.ParseQuery(cleaned)
+	return d.preProcessor.ParseQuery(cleaned)
+}
+
+func (d *Database) auth(session *network.Session, name, password string) (network.Response, error) {
+	if d.authenticator == nil {
+		err := errors.New("authentication is not enabled")
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+
+	user, err := d.authenticator.Authenticate(name, password)
 	if err != nil {
-		return network.CannotParseQuery, err
+		return network.ErrorResponse(network.StatusUnauthenticated, err), err
+	}
+
+	session.User = user
+	return network.OKResponse(), nil
+}
+
+// authorize проверяет права сессии до выполнения команды
+func (d *Database) authorize(session *network.Session, command Command, query compute.Query) (network.Response, error) {
+	if d.authenticator == nil {
+		return network.Response{}, nil
+	}
+
+	if session.User == nil {
+		err := errors.New("authentication required")
+		return network.ErrorResponse(network.StatusUnauthenticated, err), err
 	}
 
-	if useWal {
-		if _, exists := wal.WalCommands[query.CommandId]; exists {
-			err = d.wal.Append(cleaned)
-			if err != nil {
-				return network.CommandStoreError, err
-			}
+	write := command.Flags&FlagWrite != 0
+	if command.Flags&FlagAllKeys != 0 {
+		if err := session.User.AuthorizeAll(command.permission(), write); err != nil {
+			return network.ErrorResponse(network.StatusForbidden, err), err
 		}
+		return network.Response{}, nil
 	}
 
-	args := query.Args
+	var keys []string
+	if command.Keys != nil {
+		keys = command.Keys(query.Args)
+	}
+
+	if err := session.User.Authorize(command.permission(), keys, write); err != nil {
+		return network.ErrorResponse(network.StatusForbidden, err), err
+	}
+
+	return network.Response{}, nil
+}
+
+// walCommit возвращает запись в WAL для команд, которые пишутся под блокировкой ключа после проверки типа значения,
+// чтобы в WAL не попадали команды, которые не применятся при восстановлении
+func (d *keyspace) walCommit(timings *Timings) func(query compute.Query) error {
+	return func(query compute.Query) error {
+		start := time.Now()
+		defer func() {
+			timings.Wal += time.Since(start)
+		}()
+
+		if err := d.wal.Append(compute.EncodeRecord(d.index, query)); err != nil {
+			return fmt.Errorf("command storing failed: %w", err)
+		}
+		return nil
+	}
+}
+
+// checkType возвращает engine.ErrWrongType, если ключ существует и его значение не типа want
+func checkType(value engine.Value, want engine.Type) error {
+	if value.Type != engine.TypeNone && value.Type != want {
+		return engine.ErrWrongType
+	}
+	return nil
+}
+
+// typedErrorResponse - ответ на ошибку команды над хешем, списком или множеством
+func typedErrorResponse(err error) network.Response {
+	if errors.Is(err, engine.ErrWrongType) {
+		return network.ErrorResponse(network.StatusWrongType, err)
+	}
+	return network.ErrorResponse(network.StatusStoreError, err)
+}
+
+// splitPairs разделяет аргументы MSET "k1 v1 k2 v2" на ключи и значения
+func splitPairs(args []string) ([]string, []string) {
+	keys := make([]string, 0, len(args)/2)
+	values := make([]string, 0, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		keys = append(keys, args[i])
+		values = append(values, args[i+1])
+	}
+	return keys, values
+}
 
-	switch query.CommandId {
-	case compute.SetCommandId:
-		d.engine.Set(args[0], args[1])
-		return network.SuccessCommand, nil
-	case compute.GetCommandId:
-		return fmt.Sprintf(network.GetResult, d.engine.Get(args[0])), nil
-	case compute.DelCommandId:
-		d.engine.Del(args[0])
-		return network.SuccessCommand, nil
-	default:
-		return fmt.Sprintf(network.UnknownCommand, query.CommandId), err
+// notify отправляет событие изменения ключа подписчикам NOTIFY этой базы. При восстановлении из WAL live == false
+func (d *keyspace) notify(live bool, key, event string) {
+	if live {
+		d.broker.PublishKeyEvent(d.index, key, event)
 	}
 }
 
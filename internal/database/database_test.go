@@ -5,11 +5,20 @@ package database_test
 import (
 	"concurrency_hw/internal/config"
 	"concurrency_hw/internal/creator"
+	"concurrency_hw/internal/database"
+	"concurrency_hw/internal/database/compute"
 	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"concurrency_hw/internal/database/storage/wal"
+	"errors"
 	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -32,10 +41,12 @@ func TestDatabase_Execute(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	session := network.NewSession("test")
+
 	tests := []struct {
 		name        string
 		query       string
-		want        string
+		want        network.Response
 		wantErr     bool
 		setupFunc   func()
 		cleanupFunc func()
@@ -43,46 +54,51 @@ func TestDatabase_Execute(t *testing.T) {
 		{
 			name:  "SET command success",
 			query: "SET key1 value1",
-			want:  network.SuccessCommand,
+			want:  network.OKResponse(),
 		},
 		{
 			name:  "GET command success",
 			query: "GET key2",
-			want:  "[success] value2",
+			want:  network.ValueResponse("value2"),
 			setupFunc: func() {
-				_, _ = db.Execute("SET key2 value2")
+				_, _ = db.Execute(session, "SET key2 value2")
 			},
 		},
 		{
 			name:  "DEL command success",
 			query: "DEL key3",
-			want:  network.SuccessCommand,
+			want:  network.OKResponse(),
 			setupFunc: func() {
-				_, _ = db.Execute("SET key3 value3")
+				_, _ = db.Execute(session, "SET key3 value3")
 			},
 		},
+		{
+			name:  "GET missing key",
+			query: "GET missing",
+			want:  network.NotFoundResponse(),
+		},
 		{
 			name:    "Invalid command",
 			query:   "INVALID key value",
-			want:    "[error] cannot parse query",
+			want:    network.ErrorResponse(network.StatusParseError, errors.New("invalid command token: INVALID")),
 			wantErr: true,
 		},
 		{
 			name:    "Invalid SET syntax",
 			query:   "SET key",
-			want:    network.CannotParseQuery,
+			want:    network.ErrorResponse(network.StatusParseError, errors.New("invalid count of arguments")),
 			wantErr: true,
 		},
 		{
 			name:    "Invalid GET syntax",
 			query:   "GET",
-			want:    network.CannotParseQuery,
+			want:    network.ErrorResponse(network.StatusParseError, errors.New("invalid count of arguments")),
 			wantErr: true,
 		},
 		{
 			name:    "Invalid DEL syntax",
 			query:   "DEL",
-			want:    network.CannotParseQuery,
+			want:    network.ErrorResponse(network.StatusParseError, errors.New("invalid count of arguments")),
 			wantErr: true,
 		},
 	}
@@ -93,16 +109,14 @@ func TestDatabase_Execute(t *testing.T) {
 				tt.setupFunc()
 			}
 
-			got, err := db.Execute(tt.query)
+			got, err := db.Execute(session, tt.query)
 
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Database.Execute() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
 
-			if got != tt.want {
-				t.Errorf("Database.Execute() = %v, want %v", got, tt.want)
-			}
+			assert.Equal(t, tt.want, got)
 
 			if tt.cleanupFunc != nil {
 				tt.cleanupFunc()
@@ -112,10 +126,10 @@ func TestDatabase_Execute(t *testing.T) {
 
 	t.Run("Check WAL state", func(t *testing.T) {
 		expected := map[int]string{
-			0: "SET key1 value1",
-			1: "SET key2 value2",
-			2: "SET key3 value3",
-			3: "DEL key3",
+			0: "@0 SET key1 value1",
+			1: "@0 SET key2 value2",
+			2: "@0 SET key3 value3",
+			3: "@0 DEL key3",
 		}
 
 		var idx int
@@ -138,17 +152,17 @@ func TestDatabase_Execute(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		res, err := db2.Execute("GET key1")
+		res, err := db2.Execute(session, "GET key1")
 		require.NoError(t, err)
-		assert.Equal(t, fmt.Sprintf(network.GetResult, "value1"), res)
+		assert.Equal(t, network.ValueResponse("value1"), res)
 
-		res, err = db2.Execute("GET key2")
+		res, err = db2.Execute(session, "GET key2")
 		require.NoError(t, err)
-		assert.Equal(t, fmt.Sprintf(network.GetResult, "value2"), res)
+		assert.Equal(t, network.ValueResponse("value2"), res)
 
-		res, err = db2.Execute("GET key3")
+		res, err = db2.Execute(session, "GET key3")
 		require.NoError(t, err)
-		assert.Equal(t, fmt.Sprintf(network.GetResult, ""), res)
+		assert.Equal(t, network.NotFoundResponse(), res)
 
 		err = db2.Stop()
 		if err != nil {
@@ -170,13 +184,13 @@ func TestDatabase_Execute(t *testing.T) {
 		}
 
 		// Проверяем что ключа нет
-		res, err := db2.Execute("GET key1")
+		res, err := db2.Execute(session, "GET key1")
 		require.NoError(t, err)
-		assert.Equal(t, fmt.Sprintf(network.GetResult, ""), res)
+		assert.Equal(t, network.NotFoundResponse(), res)
 
-		res, err = db2.Execute("SET key1 value1")
+		res, err = db2.Execute(session, "SET key1 value1")
 		require.NoError(t, err)
-		assert.Equal(t, network.SuccessCommand, res)
+		assert.Equal(t, network.OKResponse(), res)
 
 		// Останавливаем БД - WAL должен записаться на диск
 		err = db2.Stop()
@@ -186,7 +200,7 @@ func TestDatabase_Execute(t *testing.T) {
 
 		// Проверяем, что в WAL сохранены все запросы
 		_ = tmpWal.ForEach(func(queryString string) error {
-			assert.Equal(t, "SET key1 value1", queryString)
+			assert.Equal(t, "@0 SET key1 value1", queryString)
 			return nil
 		})
 
@@ -194,6 +208,1299 @@ func TestDatabase_Execute(t *testing.T) {
 	})
 }
 
+func TestDatabase_Auth(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	require.NoError(t, registerReaderCommands())
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	conf.AuthConfig = &config.AuthConfig{
+		Enabled: true,
+		Users: []config.UserConfig{
+			{
+				Name:         "reader",
+				PasswordHash: string(hash),
+				Commands:     []string{"GET"},
+				ReadKeys:     []string{"user:*"},
+			},
+			{
+				Name:         "admin",
+				PasswordHash: string(hash),
+				Commands:     []string{"*"},
+				ReadKeys:     []string{"*"},
+				WriteKeys:    []string{"*"},
+			},
+			{
+				Name:         "writer",
+				PasswordHash: string(hash),
+				Commands:     []string{"*"},
+				ReadKeys:     []string{"*"},
+				WriteKeys:    []string{"user:*"},
+			},
+		},
+	}
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	anonymous := network.NewSession("anonymous")
+	reader := network.NewSession("reader")
+	admin := network.NewSession("admin")
+
+	t.Run("Unauthenticated session is limited to AUTH and PING", func(t *testing.T) {
+		res, err := db.Execute(anonymous, "PING")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("PONG"), res)
+
+		res, err = db.Execute(anonymous, "GET user:1")
+		assert.Error(t, err)
+		assert.Equal(t, network.StatusUnauthenticated, res.Status)
+	})
+
+	t.Run("Invalid password", func(t *testing.T) {
+		res, err := db.Execute(anonymous, "AUTH admin wrong")
+		assert.Error(t, err)
+		assert.Equal(t, network.StatusUnauthenticated, res.Status)
+		assert.Nil(t, anonymous.User)
+	})
+
+	t.Run("Per-user rules", func(t *testing.T) {
+		res, err := db.Execute(admin, "AUTH admin secret")
+		require.NoError(t, err)
+		assert.Equal(t, network.OKResponse(), res)
+
+		res, err = db.Execute(reader, "AUTH reader secret")
+		require.NoError(t, err)
+		assert.Equal(t, network.OKResponse(), res)
+
+		res, err = db.Execute(admin, "SET user:1 name")
+		require.NoError(t, err)
+		assert.Equal(t, network.OKResponse(), res)
+
+		res, err = db.Execute(reader, "GET user:1")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("name"), res)
+
+		res, err = db.Execute(reader, "GET secret:1")
+		assert.Error(t, err)
+		assert.Equal(t, network.StatusForbidden, res.Status)
+
+		res, err = db.Execute(reader, "SET user:1 other")
+		assert.Error(t, err)
+		assert.Equal(t, network.StatusForbidden, res.Status)
+	})
+
+	t.Run("Custom command uses its permission", func(t *testing.T) {
+		res, err := db.Execute(reader, "STRLEN user:1")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("4"), res)
+
+		res, err = db.Execute(reader, "STRLEN secret:1")
+		assert.Error(t, err)
+		assert.Equal(t, network.StatusForbidden, res.Status)
+		_, err = db.Execute(admin, "SET secret:1 value")
+		require.NoError(t, err)
+
+		res, err = db.Execute(reader, "LOOKUP *:1")
+		require.NoError(t, err)
+		value := "user:1"
+		assert.Equal(t, network.ValuesResponse([]*string{&value}), res)
+	})
+
+	t.Run("Database-wide commands require access to all keys", func(t *testing.T) {
+		writer := network.NewSession("writer")
+		_, err := db.Execute(writer, "AUTH writer secret")
+		require.NoError(t, err)
+
+		for _, query := range []string{"FLUSHDB", "FLUSHALL", "SWAPDB 0 1"} {
+			res, err := db.Execute(writer, query)
+			assert.Error(t, err, query)
+			assert.Equal(t, network.StatusForbidden, res.Status, query)
+		}
+
+		res, err := db.Execute(writer, "GET user:1")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("name"), res)
+
+		res, err = db.Execute(admin, "FLUSHDB")
+		require.NoError(t, err)
+		assert.Equal(t, network.OKResponse(), res)
+	})
+}
+
+func TestDatabase_PubSub(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	subscriber := network.NewSession("subscriber")
+	publisher := network.NewSession("publisher")
+
+	res, err := db.Execute(subscriber, "SUBSCRIBE news")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+	require.NotNil(t, subscriber.PushSource())
+
+	t.Run("Subscribed mode allows only pub/sub commands and PING", func(t *testing.T) {
+		res, err := db.Execute(subscriber, "GET key")
+		assert.Error(t, err)
+		assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+		res, err = db.Execute(subscriber, "PING")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("PONG"), res)
+	})
+
+	t.Run("Publish", func(t *testing.T) {
+		res, err := db.Execute(publisher, "PUBLISH news hello")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("1"), res)
+
+		assert.Equal(t, network.MessageResponse("news", "hello"), <-subscriber.PushSource().Messages())
+	})
+
+	t.Run("Unsubscribe leaves subscribed mode", func(t *testing.T) {
+		res, err := db.Execute(subscriber, "UNSUBSCRIBE news")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("0"), res)
+
+		res, err = db.Execute(subscriber, "GET key")
+		require.NoError(t, err)
+		assert.Equal(t, network.NotFoundResponse(), res)
+	})
+
+	t.Run("Key events are sent after writes", func(t *testing.T) {
+		watcher := network.NewSession("watcher")
+		defer watcher.Close()
+
+		res, err := db.Execute(watcher, "NOTIFY user:*")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("1"), res)
+
+		_, err = db.Execute(publisher, "SET user:1 name")
+		require.NoError(t, err)
+		_, err = db.Execute(publisher, "SET order:1 item")
+		require.NoError(t, err)
+		_, err = db.Execute(publisher, "DEL user:1")
+		require.NoError(t, err)
+
+		// Подписчик получает события только базы, выбранной при NOTIFY
+		other := network.NewSession("other")
+		other.DB = 1
+		_, err = db.Execute(other, "SET user:2 name")
+		require.NoError(t, err)
+
+		messages := watcher.PushSource().Messages()
+		assert.Equal(t, network.EventResponse("user:1", database.KeyEventSet), <-messages)
+		assert.Equal(t, network.EventResponse("user:1", database.KeyEventDel), <-messages)
+		assert.Len(t, messages, 0)
+	})
+
+	t.Run("Closed session is removed from the broker", func(t *testing.T) {
+		_, err := db.Execute(subscriber, "SUBSCRIBE news")
+		require.NoError(t, err)
+
+		subscriber.Close()
+		<-subscriber.PushSource().Done()
+
+		res, err := db.Execute(publisher, "PUBLISH news hello")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("0"), res)
+	})
+}
+
+func TestDatabase_Admin(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("admin")
+
+	for _, query := range []string{"SET a 1", "SET b 2", "SET a 3"} {
+		_, err := db.Execute(session, query)
+		require.NoError(t, err)
+	}
+
+	res, err := db.Execute(session, "DBSIZE")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	res, err = db.Execute(session, "INFO")
+	require.NoError(t, err)
+	require.NotNil(t, res.Value)
+	assert.Contains(t, *res.Value, "keys:2")
+	assert.Contains(t, *res.Value, "connected_clients:0")
+	assert.Contains(t, *res.Value, "wal_segments:1")
+
+	res, err = db.Execute(session, "CLIENT LIST")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse(""), res)
+
+	res, err = db.Execute(session, "CLIENT KILL 42")
+	require.NoError(t, err)
+	assert.Equal(t, network.NotFoundResponse(), res)
+
+	res, err = db.Execute(session, "CLIENT KILL abc")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+}
+
+func TestDatabase_SlowLog(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+	conf.SlowLogConfig = &config.SlowLogConfig{Threshold: 0, MaxLen: 2}
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("admin")
+
+	for _, query := range []string{"SET a 1", "GET a", "SET b 2"} {
+		_, err := db.Execute(session, query)
+		require.NoError(t, err)
+	}
+
+	res, err := db.Execute(session, "SLOWLOG GET")
+	require.NoError(t, err)
+	require.NotNil(t, res.Value)
+
+	lines := strings.Split(*res.Value, "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "id=3 ")
+	assert.Contains(t, lines[0], "query=SET b 2")
+	assert.Contains(t, lines[1], "id=2 ")
+	assert.Contains(t, lines[1], "query=GET a")
+
+	res, err = db.Execute(session, "SLOWLOG RESET")
+	require.NoError(t, err)
+	assert.Equal(t, network.OKResponse(), res)
+
+	// Сам SLOWLOG RESET попадает в журнал уже после очистки
+	res, err = db.Execute(session, "SLOWLOG GET")
+	require.NoError(t, err)
+	require.NotNil(t, res.Value)
+	assert.Contains(t, *res.Value, "query=SLOWLOG RESET")
+	assert.NotContains(t, *res.Value, "query=SET")
+}
+
+func TestDatabase_Monitor(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	monitorSession := network.NewSession("127.0.0.1:5000")
+	res, err := db.Execute(monitorSession, "MONITOR")
+	require.NoError(t, err)
+	assert.Equal(t, network.OKResponse(), res)
+	require.NotNil(t, monitorSession.PushSource())
+	<-monitorSession.PushSource().Messages()
+
+	res, err = db.Execute(monitorSession, "GET a")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+	<-monitorSession.PushSource().Messages()
+
+	session := network.NewSession("127.0.0.1:6000")
+	_, err = db.Execute(session, "SET a 1")
+	require.NoError(t, err)
+
+	message := <-monitorSession.PushSource().Messages()
+	assert.Equal(t, "127.0.0.1:6000", message.Channel)
+	require.NotNil(t, message.Value)
+	assert.True(t, strings.HasSuffix(*message.Value, " ok SET a 1"), *message.Value)
+
+	monitorSession.Close()
+	<-monitorSession.PushSource().Done()
+}
+
+func TestDatabase_QuotedValues(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	value := "multi line\nvalue with \"quotes\" and \xff byte"
+	session := network.NewSession("127.0.0.1:5000")
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	_, err = db.Execute(session, `SET key "multi line\nvalue with \"quotes\" and \xff byte"`)
+	require.NoError(t, err)
+
+	res, err := db.Execute(session, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse(value), res)
+
+	res, err = db.Execute(session, `SET key "unterminated`)
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusParseError, res.Status)
+	assert.Contains(t, res.Error, "at position 9")
+
+	require.NoError(t, db.Stop())
+
+	// После перезапуска значение восстанавливается из WAL без изменений
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse(value), res)
+}
+
+func TestDatabase_LegacyWal(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	// WAL до появления кавычек: токены разделены пробелами, кавычка - обычный символ значения
+	_, segment, err := wal.NewStringSegmentReader(conf.WalConfig)
+	require.NoError(t, err)
+	writer, err := wal.NewStringSegmentWriter(conf.WalConfig, segment)
+	require.NoError(t, err)
+	require.NoError(t, writer.Write([]string{`SET quoted "abc`, `SET plain value`, `DEL plain`}))
+	require.NoError(t, writer.Close())
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("127.0.0.1:5000")
+
+	res, err := db.Execute(session, "GET quoted")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse(`"abc`), res)
+
+	res, err = db.Execute(session, "GET plain")
+	require.NoError(t, err)
+	assert.Equal(t, network.NotFoundResponse(), res)
+}
+
+func TestDatabase_MultiKey(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	session := network.NewSession("127.0.0.1:5000")
+	value := func(v string) *string { return &v }
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	res, err := db.Execute(session, `MSET a 1 b "two words" c 3`)
+	require.NoError(t, err)
+	assert.Equal(t, network.OKResponse(), res)
+
+	res, err = db.Execute(session, "MDEL c missing")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+
+	res, err = db.Execute(session, "MSET a 1 b")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusParseError, res.Status)
+
+	require.NoError(t, db.Stop())
+
+	// MSET и MDEL восстанавливаются из WAL целиком
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "MGET a b c")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("1"), value("two words"), nil}), res)
+}
+
+func TestDatabase_Counters(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	session := network.NewSession("127.0.0.1:5000")
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	tests := []struct {
+		query  string
+		result network.Response
+	}{
+		{"INCR counter", network.ValueResponse("1")},
+		{"INCRBY counter 10", network.ValueResponse("11")},
+		{"DECR counter", network.ValueResponse("10")},
+		{"DECRBY counter 25", network.ValueResponse("-15")},
+	}
+	for _, tt := range tests {
+		res, err := db.Execute(session, tt.query)
+		require.NoError(t, err, tt.query)
+		assert.Equal(t, tt.result, res, tt.query)
+	}
+
+	_, err = db.Execute(session, "SET text abc")
+	require.NoError(t, err)
+	res, err := db.Execute(session, "INCR text")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusNotInteger, res.Status)
+
+	_, err = db.Execute(session, "SET big 9223372036854775800")
+	require.NoError(t, err)
+	res, err = db.Execute(session, "INCRBY big 100")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusOverflow, res.Status)
+
+	res, err = db.Execute(session, "DECRBY big -9223372036854775808")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusOverflow, res.Status)
+
+	res, err = db.Execute(session, "INCRBY counter ten")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	require.NoError(t, db.Stop())
+
+	// В WAL записан результат, а не приращение
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "GET counter")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("-15"), res)
+}
+
+func TestDatabase_ConcurrentIncr(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := db.Execute(network.NewSession("127.0.0.1:5000"), "INCR counter")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	res, err := db.Execute(network.NewSession("127.0.0.1:5000"), "GET counter")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("50"), res)
+}
+
+func TestDatabase_ConcurrentSetAndIncrReplay(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			query := "INCR counter"
+			if i%5 == 0 {
+				query = fmt.Sprintf("SET counter %d", i)
+			}
+			_, err := db.Execute(network.NewSession("127.0.0.1:5000"), query)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	session := network.NewSession("127.0.0.1:5000")
+	before, err := db.Execute(session, "GET counter")
+	require.NoError(t, err)
+	require.NoError(t, db.Stop())
+
+	// SET и INCR пишутся в WAL под блокировкой ключа, поэтому восстановление дает то же значение
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	after, err := db.Execute(session, "GET counter")
+	require.NoError(t, err)
+	assert.Equal(t, before, after)
+}
+
+func TestDatabase_Hash(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	session := network.NewSession("127.0.0.1:5000")
+	value := func(v string) *string { return &v }
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	res, err := db.Execute(session, `HSET user:1 name "Bob Smith" age 30 city Moscow`)
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("3"), res)
+
+	res, err = db.Execute(session, "HSET user:1 age 31")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("0"), res)
+
+	res, err = db.Execute(session, "HDEL user:1 city missing")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+
+	res, err = db.Execute(session, "SET plain value")
+	require.NoError(t, err)
+
+	// Команды над ключом другого типа не меняют его и не пишутся в WAL
+	res, err = db.Execute(session, "HSET plain field value")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	res, err = db.Execute(session, "GET user:1")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	res, err = db.Execute(session, "INCR user:1")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	res, err = db.Execute(session, "HLEN plain")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	res, err = db.Execute(session, "HSET empty f v")
+	require.NoError(t, err)
+	res, err = db.Execute(session, "HDEL empty f")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Stop())
+
+	// Хеши восстанавливаются из WAL
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "HGETALL user:1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("age"), value("31"), value("name"), value("Bob Smith")}), res)
+
+	res, err = db.Execute(session, "HGET user:1 name")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("Bob Smith"), res)
+
+	res, err = db.Execute(session, "HGET user:1 city")
+	require.NoError(t, err)
+	assert.Equal(t, network.NotFoundResponse(), res)
+
+	res, err = db.Execute(session, "HLEN user:1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	res, err = db.Execute(session, "HEXISTS user:1 age")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+
+	res, err = db.Execute(session, "HGETALL missing")
+	require.NoError(t, err)
+	assert.Empty(t, res.Values)
+
+	// Хеш без полей удаляется
+	res, err = db.Execute(session, "EXISTS user:1 plain empty")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	res, err = db.Execute(session, "MGET user:1 plain")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{nil, value("value")}), res)
+
+	// SET заменяет значение любого типа
+	_, err = db.Execute(session, "SET user:1 replaced")
+	require.NoError(t, err)
+	res, err = db.Execute(session, "GET user:1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("replaced"), res)
+}
+
+func TestDatabase_List(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	session := network.NewSession("127.0.0.1:5000")
+	value := func(v string) *string { return &v }
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	res, err := db.Execute(session, "RPUSH jobs b c")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	res, err = db.Execute(session, "LPUSH jobs a z")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("4"), res)
+
+	res, err = db.Execute(session, "LPOP jobs")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("z"), res)
+
+	res, err = db.Execute(session, "RPOP jobs")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("c"), res)
+
+	res, err = db.Execute(session, "LPOP missing")
+	require.NoError(t, err)
+	assert.Equal(t, network.NotFoundResponse(), res)
+
+	_, err = db.Execute(session, "SET plain value")
+	require.NoError(t, err)
+	res, err = db.Execute(session, "RPUSH plain x")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	res, err = db.Execute(session, "LRANGE jobs 0 one")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	require.NoError(t, db.Stop())
+
+	// Списки восстанавливаются из WAL
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "LRANGE jobs 0 -1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("a"), value("b")}), res)
+
+	res, err = db.Execute(session, "LRANGE jobs -1 10")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("b")}), res)
+
+	res, err = db.Execute(session, "LLEN jobs")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	// Список без элементов удаляется
+	_, err = db.Execute(session, "LPOP jobs")
+	require.NoError(t, err)
+	_, err = db.Execute(session, "LPOP jobs")
+	require.NoError(t, err)
+	res, err = db.Execute(session, "EXISTS jobs")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("0"), res)
+}
+
+func TestDatabase_BLPop(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	session := network.NewSession("127.0.0.1:5000")
+	value := func(v string) *string { return &v }
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	res, err := db.Execute(session, "BLPOP jobs 0.05")
+	require.NoError(t, err)
+	assert.Equal(t, network.NotFoundResponse(), res)
+
+	res, err = db.Execute(session, "BLPOP jobs -1")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	// Ожидающие получают элементы в порядке прихода
+	results := make([]chan network.Response, 2)
+	for i := range results {
+		results[i] = make(chan network.Response, 1)
+		go func() {
+			res, _ := db.Execute(network.NewSession("127.0.0.1:5001"), "BLPOP jobs 0")
+			results[i] <- res
+		}()
+
+		require.Eventually(t, func() bool {
+			res, _ := db.Execute(session, "INFO")
+			return strings.Contains(*res.Value, fmt.Sprintf("blocked_clients:%d", i+1))
+		}, time.Second, time.Millisecond)
+	}
+
+	res, err = db.Execute(session, "RPUSH jobs a b c")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("3"), res)
+	assert.Equal(t, network.ValueResponse("a"), <-results[0])
+	assert.Equal(t, network.ValueResponse("b"), <-results[1])
+
+	res, err = db.Execute(session, "BLPOP jobs 1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("c"), res)
+
+	// Закрытие сессии прерывает ожидание
+	blocked := network.NewSession("127.0.0.1:5002")
+	done := make(chan error, 1)
+	go func() {
+		_, err := db.Execute(blocked, "BLPOP jobs 0")
+		done <- err
+	}()
+	require.Eventually(t, func() bool {
+		res, _ := db.Execute(session, "INFO")
+		return strings.Contains(*res.Value, "blocked_clients:1")
+	}, time.Second, time.Millisecond)
+	blocked.Close()
+	assert.Error(t, <-done)
+
+	// Элемент, выданный сессии, которая закрылась до ответа, возвращается в список
+	popped := make(chan network.Response, 1)
+	for i := range 20 {
+		blocked := network.NewSession("127.0.0.1:5003")
+		go func() {
+			res, _ := db.Execute(blocked, "BLPOP tasks 0")
+			popped <- res
+		}()
+		require.Eventually(t, func() bool {
+			res, _ := db.Execute(session, "INFO")
+			return strings.Contains(*res.Value, "blocked_clients:1")
+		}, time.Second, time.Millisecond)
+
+		item := fmt.Sprintf("t%d", i)
+		pushed := make(chan error, 1)
+		go func() {
+			_, err := db.Execute(session, "RPUSH tasks "+item)
+			pushed <- err
+		}()
+		blocked.Close()
+		require.NoError(t, <-pushed)
+
+		if res := <-popped; !res.IsError() {
+			// Сессия получила элемент до закрытия
+			assert.Equal(t, network.ValueResponse(item), res)
+			continue
+		}
+		res, err = db.Execute(session, "LPOP tasks")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse(item), res)
+	}
+
+	_, err = db.Execute(session, "RPUSH jobs d")
+	require.NoError(t, err)
+
+	require.NoError(t, db.Stop())
+
+	// Элементы, выданные ожидающим, не восстанавливаются из WAL
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "LRANGE jobs 0 -1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("d")}), res)
+}
+
+func TestDatabase_Sets(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	session := network.NewSession("127.0.0.1:5000")
+	value := func(v string) *string { return &v }
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	res, err := db.Execute(session, "SADD tags go db go cache")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("3"), res)
+
+	res, err = db.Execute(session, "SREM tags cache missing")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+
+	res, err = db.Execute(session, "ZADD board 30 carol 10 alice 20 bob")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("3"), res)
+
+	res, err = db.Execute(session, "ZADD board 25.5 alice")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("0"), res)
+
+	res, err = db.Execute(session, "ZREM board carol")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+
+	res, err = db.Execute(session, "ZADD board high dave")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	res, err = db.Execute(session, "ZADD tags 1 go")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	res, err = db.Execute(session, "SCARD board")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusWrongType, res.Status)
+
+	require.NoError(t, db.Stop())
+
+	// Множества восстанавливаются из WAL
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "SMEMBERS tags")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("db"), value("go")}), res)
+
+	res, err = db.Execute(session, "SISMEMBER tags go")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), res)
+
+	res, err = db.Execute(session, "SCARD tags")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	res, err = db.Execute(session, "ZRANGE board 0 -1 WITHSCORES")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("bob"), value("20"), value("alice"), value("25.5")}), res)
+
+	res, err = db.Execute(session, "ZSCORE board alice")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("25.5"), res)
+
+	res, err = db.Execute(session, "ZSCORE board carol")
+	require.NoError(t, err)
+	assert.Equal(t, network.NotFoundResponse(), res)
+
+	_, err = db.Execute(session, "ZADD board -inf zed 40 eve")
+	require.NoError(t, err)
+
+	res, err = db.Execute(session, "ZRANGEBYSCORE board (20 +inf")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("alice"), value("eve")}), res)
+
+	res, err = db.Execute(session, "ZRANGEBYSCORE board -inf 30 WITHSCORES LIMIT 1 1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{value("bob"), value("20")}), res)
+
+	res, err = db.Execute(session, "ZRANGE board 0 -1 LIMIT 0 1")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	res, err = db.Execute(session, "ZRANGE missing 0 -1")
+	require.NoError(t, err)
+	assert.Empty(t, res.Values)
+}
+
+func TestDatabase_Keys(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("127.0.0.1:5000")
+	for i := range 30 {
+		_, err := db.Execute(session, fmt.Sprintf("SET user:%d value", i))
+		require.NoError(t, err)
+	}
+	_, err = db.Execute(session, "SET order:1 value")
+	require.NoError(t, err)
+
+	seen := make(map[string]bool)
+	cursor := "0"
+	for {
+		res, err := db.Execute(session, "SCAN "+cursor+" MATCH user:* COUNT 5")
+		require.NoError(t, err)
+		require.NotEmpty(t, res.Values)
+
+		for _, key := range res.Values[1:] {
+			assert.True(t, strings.HasPrefix(*key, "user:"))
+			seen[*key] = true
+		}
+
+		cursor = *res.Values[0]
+		if cursor == "0" {
+			break
+		}
+	}
+	assert.Len(t, seen, 30)
+
+	res, err := db.Execute(session, "KEYS order:*")
+	require.NoError(t, err)
+	require.Len(t, res.Values, 1)
+	assert.Equal(t, "order:1", *res.Values[0])
+
+	res, err = db.Execute(session, "EXISTS user:1 user:1 missing order:1")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("3"), res)
+
+	res, err = db.Execute(session, "SCAN 0 LIMIT 5")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	res, err = db.Execute(session, "SCAN oops")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+}
+
+func TestDatabase_OrderedKeys(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+	conf.EngineConfig.Type = creator.EngineTypeSkipList
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("127.0.0.1:5000")
+	_, err = db.Execute(session, "MSET b 1 a 1 ab 1 ac 1 c 1")
+	require.NoError(t, err)
+
+	values := func(res network.Response) []string {
+		keys := make([]string, 0, len(res.Values))
+		for _, key := range res.Values {
+			keys = append(keys, *key)
+		}
+		return keys
+	}
+
+	res, err := db.Execute(session, "RANGE a c")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "ab", "ac", "b"}, values(res))
+
+	res, err = db.Execute(session, `RANGE a "" LIMIT 2`)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "ab"}, values(res))
+
+	res, err = db.Execute(session, "PREFIX a")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "ab", "ac"}, values(res))
+
+	res, err = db.Execute(session, "PREFIX a LIMIT 0")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	res, err = db.Execute(session, "PREFIX a COUNT 1")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusInvalidArgument, res.Status)
+
+	conf.EngineConfig.Type = creator.EngineTypeInMemory
+	conf.WalConfig.DataDirectory = t.TempDir()
+	unordered, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, unordered.Stop())
+	}()
+
+	res, err = unordered.Execute(session, "RANGE a c")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusUnsupported, res.Status)
+}
+
+func TestDatabase_Databases(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+	conf.EngineConfig.Databases = 4
+
+	first := network.NewSession("127.0.0.1:5000")
+	second := network.NewSession("127.0.0.1:5001")
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	// Базы независимы, SELECT действует только на свою сессию
+	_, err = db.Execute(first, "SET key zero")
+	require.NoError(t, err)
+	res, err := db.Execute(second, "SELECT 1")
+	require.NoError(t, err)
+	assert.Equal(t, network.OKResponse(), res)
+	_, err = db.Execute(second, "SET key one")
+	require.NoError(t, err)
+	_, err = db.Execute(second, "RPUSH list a b")
+	require.NoError(t, err)
+
+	res, err = db.Execute(first, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("zero"), res)
+	res, err = db.Execute(second, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("one"), res)
+	res, err = db.Execute(second, "DBSIZE")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("2"), res)
+
+	res, err = db.Execute(first, "INFO")
+	require.NoError(t, err)
+	assert.Contains(t, *res.Value, "keys:3")
+	assert.Contains(t, *res.Value, "db0:keys=1")
+	assert.Contains(t, *res.Value, "db1:keys=2")
+	assert.Equal(t, 3, db.Len())
+
+	for _, query := range []string{"SELECT 4", "SELECT -1", "SELECT one", "SWAPDB 0 4"} {
+		res, err = db.Execute(first, query)
+		assert.Error(t, err, query)
+		assert.Equal(t, network.StatusInvalidArgument, res.Status, query)
+	}
+
+	// SWAPDB меняет содержимое баз местами для всех сессий
+	res, err = db.Execute(first, "SWAPDB 0 1")
+	require.NoError(t, err)
+	assert.Equal(t, network.OKResponse(), res)
+	res, err = db.Execute(first, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("one"), res)
+
+	_, err = db.Execute(second, "SET other value")
+	require.NoError(t, err)
+	_, err = db.Execute(first, "SELECT 2")
+	require.NoError(t, err)
+	_, err = db.Execute(first, "SET third value")
+	require.NoError(t, err)
+	_, err = db.Execute(first, "FLUSHDB")
+	require.NoError(t, err)
+	res, err = db.Execute(first, "DBSIZE")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("0"), res)
+
+	require.NoError(t, db.Stop())
+
+	// Записи WAL восстанавливаются в свои базы с учетом SWAPDB и FLUSHDB
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("127.0.0.1:5002")
+	res, err = db.Execute(session, "LRANGE list 0 -1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(res.Values))
+	res, err = db.Execute(session, "GET key")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("one"), res)
+
+	_, err = db.Execute(session, "SELECT 1")
+	require.NoError(t, err)
+	res, err = db.Execute(session, "MGET key other")
+	require.NoError(t, err)
+	value := func(v string) *string { return &v }
+	assert.Equal(t, network.ValuesResponse([]*string{value("zero"), value("value")}), res)
+	assert.Equal(t, 4, db.Len())
+
+	_, err = db.Execute(session, "FLUSHALL")
+	require.NoError(t, err)
+	assert.Equal(t, 0, db.Len())
+}
+
+// registerAppend регистрирует APPEND один раз на процесс: команды регистрируются глобально
+var registerAppend = sync.OnceValue(func() error {
+	appendValue := func(call *database.Call) (network.Response, error) {
+		key, suffix := call.Query.Args[0], call.Query.Args[1]
+
+		var result string
+		err := call.Engine().Update(key, func(value engine.Value) (engine.Value, error) {
+			if value.Type != engine.TypeNone && value.Type != engine.TypeString {
+				return value, engine.ErrWrongType
+			}
+			result = value.Str + suffix
+			// Запрос пишется в WAL под блокировкой ключа, ошибка записи отменяет изменение
+			if err := call.Log(call.Query); err != nil {
+				return value, err
+			}
+			return engine.StringValue(result), nil
+		})
+		if err != nil {
+			return network.ErrorResponse(network.StatusWrongType, err), err
+		}
+
+		call.Notify(key, database.KeyEventSet)
+		return network.ValueResponse(strconv.Itoa(len(result))), nil
+	}
+
+	return database.RegisterCommand(database.Command{
+		Name:    "APPEND",
+		Arity:   compute.Exactly(2),
+		Usage:   "key value",
+		Flags:   database.FlagWrite,
+		Keys:    func(args []string) []string { return args[:1] },
+		Handler: appendValue,
+		Replay:  appendValue,
+	})
+})
+
+// registerReaderCommands регистрирует STRLEN и LOOKUP, которые разрешены пользователям с правом на GET
+var registerReaderCommands = sync.OnceValue(func() error {
+	err := database.RegisterCommand(database.Command{
+		Name:       "STRLEN",
+		Arity:      compute.Exactly(1),
+		Usage:      "key",
+		Permission: "get",
+		Keys:       func(args []string) []string { return args },
+		Handler: func(call *database.Call) (network.Response, error) {
+			value, _, err := call.Engine().Get(call.Query.Args[0])
+			if err != nil {
+				return network.ErrorResponse(network.StatusWrongType, err), err
+			}
+			return network.ValueResponse(strconv.Itoa(len(value))), nil
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	// LOOKUP pattern - первая порция подходящих ключей, доступных сессии
+	return database.RegisterCommand(database.Command{
+		Name:       "LOOKUP",
+		Arity:      compute.Exactly(1),
+		Usage:      "pattern",
+		Permission: "GET",
+		Handler: func(call *database.Call) (network.Response, error) {
+			keys, _, err := call.Engine().Scan(engine.ScanDone, 100, call.KeyFilter(call.Query.Args[0]))
+			if err != nil {
+				return network.ErrorResponse(network.StatusInvalidArgument, err), err
+			}
+			values := make([]*string, len(keys))
+			for i := range keys {
+				values[i] = &keys[i]
+			}
+			return network.ValuesResponse(values), nil
+		},
+	})
+})
+
+func TestDatabase_Commands(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+
+	require.NoError(t, registerAppend())
+	assert.Error(t, database.RegisterCommand(database.Command{Name: "SET", Arity: compute.Exactly(2), Handler: func(*database.Call) (network.Response, error) {
+		return network.OKResponse(), nil
+	}}))
+	assert.Error(t, database.RegisterCommand(database.Command{Name: "NOHANDLER", Arity: compute.Exactly(0)}))
+
+	session := network.NewSession("127.0.0.1:5000")
+	value := func(v string) *string { return &v }
+
+	db, err := creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+
+	_, err = db.Execute(session, "SET greeting hello")
+	require.NoError(t, err)
+	res, err := db.Execute(session, "APPEND greeting \", world\"")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("12"), res)
+
+	res, err = db.Execute(session, "APPEND greeting")
+	assert.Error(t, err)
+	assert.Equal(t, network.StatusParseError, res.Status)
+
+	res, err = db.Execute(session, "COMMAND INFO APPEND GET \"CLIENT LIST\" MISSING")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{
+		value("name=APPEND arity=2 flags=write"),
+		value("name=GET arity=1 flags=read"),
+		value(`name="CLIENT LIST" arity=0 flags=read,server`),
+		nil,
+	}), res)
+
+	res, err = db.Execute(session, "COMMAND INFO client list SLOWLOG RESET CLIENT")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValuesResponse([]*string{
+		value(`name="CLIENT LIST" arity=0 flags=read,server`),
+		value(`name="SLOWLOG RESET" arity=0 flags=read,server`),
+		nil,
+	}), res)
+
+	res, err = db.Execute(session, "HELP HSET")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("HSET key field value [field value ...]"), res)
+
+	res, err = db.Execute(session, "HELP CLIENT KILL")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("CLIENT KILL id"), res)
+
+	res, err = db.Execute(session, "HELP")
+	require.NoError(t, err)
+	assert.Contains(t, *res.Value, "APPEND key value\n")
+	assert.Contains(t, *res.Value, "MSET key value [key value ...]\n")
+
+	require.NoError(t, db.Stop())
+
+	// Команда встраивающего приложения восстанавливается из WAL
+	db, err = creator.NewCreator(logger, conf).CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	res, err = db.Execute(session, "GET greeting")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("hello, world"), res)
+}
+
 func cleanup(dir string) error {
 	// Прибираемся за собой
 	err := os.RemoveAll(dir)
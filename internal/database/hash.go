@@ -0,0 +1,119 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// hashWrite выполняет HSET и HDEL. Запрос пишется в WAL под блокировкой ключа через walCommit
+func (d *keyspace) hashWrite(query compute.Query, timings *Timings) (network.Response, error) {
+	count, err := d.updateHash(query, d.walCommit(timings))
+
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+
+	event := KeyEventHSet
+	if query.CommandId == compute.HDelCommandId {
+		event = KeyEventHDel
+	}
+	d.notify(true, query.Args[0], event)
+
+	return network.ValueResponse(strconv.Itoa(count)), nil
+}
+
+// updateHash применяет HSET или HDEL к движку и возвращает число добавленных или удаленных полей.
+// commit (nil при восстановлении из WAL) вызывается до изменения, его ошибка отменяет команду.
+// Хеш без полей удаляется вместе с ключом
+func (d *keyspace) updateHash(query compute.Query, commit func(compute.Query) error) (int, error) {
+	key, args := query.Args[0], query.Args[1:]
+
+	count := 0
+	err := d.engine.Update(key, func(value engine.Value) (engine.Value, error) {
+		if err := checkType(value, engine.TypeHash); err != nil {
+			return value, err
+		}
+		if value.Type == engine.TypeNone {
+			value = engine.Value{Type: engine.TypeHash, Hash: make(map[string]string)}
+		}
+
+		if commit != nil {
+			if err := commit(query); err != nil {
+				return value, err
+			}
+		}
+
+		if query.CommandId == compute.HSetCommandId {
+			for i := 0; i+1 < len(args); i += 2 {
+				if _, exists := value.Hash[args[i]]; !exists {
+					count++
+				}
+				value.Hash[args[i]] = args[i+1]
+			}
+		} else {
+			for _, field := range args {
+				if _, exists := value.Hash[field]; exists {
+					delete(value.Hash, field)
+					count++
+				}
+			}
+		}
+
+		if len(value.Hash) == 0 {
+			return engine.Value{}, nil
+		}
+		return value, nil
+	})
+
+	return count, err
+}
+
+// hashRead выполняет HGET, HGETALL, HLEN и HEXISTS
+func (d *keyspace) hashRead(query compute.Query) (network.Response, error) {
+	var response network.Response
+	err := d.engine.View(query.Args[0], func(value engine.Value) error {
+		if err := checkType(value, engine.TypeHash); err != nil {
+			return err
+		}
+
+		switch query.CommandId {
+		case compute.HGetCommandId:
+			if field, exists := value.Hash[query.Args[1]]; exists {
+				response = network.ValueResponse(field)
+			} else {
+				response = network.NotFoundResponse()
+			}
+		case compute.HGetAllCommandId:
+			// Поля сортируются, чтобы ответ не зависел от порядка обхода map
+			fields := slices.Sorted(maps.Keys(value.Hash))
+			values := make([]*string, 0, 2*len(fields))
+			for _, field := range fields {
+				fieldValue := value.Hash[field]
+				values = append(values, &field, &fieldValue)
+			}
+			response = network.ValuesResponse(values)
+		case compute.HLenCommandId:
+			response = network.ValueResponse(strconv.Itoa(len(value.Hash)))
+		case compute.HExistsCommandId:
+			_, exists := value.Hash[query.Args[1]]
+			response = network.ValueResponse(boolValue(exists))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+	return response, nil
+}
+
+func boolValue(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
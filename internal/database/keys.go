@@ -0,0 +1,145 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/auth"
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"concurrency_hw/internal/glob"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+)
+
+const (
+	defaultScanCount = 10
+
+	scanMatchOption = "MATCH"
+	scanCountOption = "COUNT"
+	limitOption     = "LIMIT"
+)
+
+// scan - SCAN cursor [MATCH pattern] [COUNT n]. Ответ - список, первый элемент которого курсор следующей порции,
+// а остальные - ключи. Ключи, недоступные пользователю на чтение, пропускаются
+func (d *keyspace) scan(filter func(pattern string) func(key string) bool, args []string) (network.Response, error) {
+	cursor, pattern, count := args[0], "*", defaultScanCount
+
+	for i := 1; i < len(args); i += 2 {
+		switch option, value := args[i], args[i+1]; option {
+		case scanMatchOption:
+			pattern = value
+		case scanCountOption:
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				err = errors.New("COUNT must be a positive integer")
+				return network.ErrorResponse(network.StatusInvalidArgument, err), err
+			}
+			count = parsed
+		default:
+			err := fmt.Errorf("unknown SCAN option: %s", option)
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+	}
+
+	keys, next, err := d.engine.Scan(cursor, count, filter(pattern))
+	if err != nil {
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+
+	return network.ValuesResponse(toValues(append([]string{next}, keys...))), nil
+}
+
+// keys возвращает все подходящие ключи по порядку. Обходит весь движок, поэтому подходит только для небольших данных
+func (d *keyspace) keys(match func(key string) bool) (network.Response, error) {
+	var keys []string
+	cursor := engine.ScanDone
+	for {
+		batch, next, err := d.engine.Scan(cursor, defaultScanCount, match)
+		if err != nil {
+			err = fmt.Errorf("cannot iterate keys: %w", err)
+			return network.ErrorResponse(network.StatusInternalError, err), err
+		}
+
+		keys = append(keys, batch...)
+		if next == engine.ScanDone {
+			break
+		}
+		cursor = next
+	}
+
+	slices.Sort(keys)
+	return network.ValuesResponse(toValues(keys)), nil
+}
+
+// ordered - RANGE start end [LIMIT n] и PREFIX p [LIMIT n]. Возвращает доступные пользователю ключи по порядку
+func (d *keyspace) ordered(match func(key string) bool, query compute.Query) (network.Response, error) {
+	ordered, ok := d.engine.(engine.Ordered)
+	if !ok {
+		err := fmt.Errorf("%w: %s requires an ordered engine", engine.ErrUnsupported, compute.CommandName(query.CommandId))
+		return network.ErrorResponse(network.StatusUnsupported, err), err
+	}
+
+	bounds := 2
+	if query.CommandId == compute.PrefixCommandId {
+		bounds = 1
+	}
+
+	limit := 0
+	for i := bounds; i < len(query.Args); i += 2 {
+		option, value := query.Args[i], query.Args[i+1]
+		if option != limitOption {
+			err := fmt.Errorf("unknown option: %s", option)
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			err = errors.New("LIMIT must be a positive integer")
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+		limit = parsed
+	}
+
+	var keys []string
+	if query.CommandId == compute.PrefixCommandId {
+		keys = ordered.Prefix(query.Args[0], limit, match)
+	} else {
+		keys = ordered.Range(query.Args[0], query.Args[1], limit, match)
+	}
+
+	return network.ValuesResponse(toValues(keys)), nil
+}
+
+// exists возвращает число существующих ключей. Повторенный ключ считается столько раз, сколько указан
+func (d *keyspace) exists(keys []string) (network.Response, error) {
+	return network.ValueResponse(strconv.Itoa(d.engine.Exists(keys))), nil
+}
+
+// keyFilter отбирает ключи по glob-шаблону и правам пользователя на чтение по праву permission.
+// Если фильтровать нечего, возвращает nil
+func (d *Database) keyFilter(session *network.Session, permission, pattern string) func(key string) bool {
+	var user *auth.User
+	if d.authenticator != nil && session != nil {
+		user = session.User
+	}
+
+	if user == nil && pattern == "*" {
+		return nil
+	}
+
+	return func(key string) bool {
+		if !glob.Match(pattern, key) {
+			return false
+		}
+		return user == nil || user.Authorize(permission, []string{key}, false) == nil
+	}
+}
+
+func toValues(values []string) []*string {
+	result := make([]*string, len(values))
+	for i := range values {
+		result[i] = &values[i]
+	}
+	return result
+}
@@ -0,0 +1,138 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// keyspace - одна из пронумерованных баз. Команды над данными - методы keyspace, поэтому d.engine в них -
+// движок базы, выбранной сессией, а записи WAL помечаются номером этой базы
+type keyspace struct {
+	*Database
+	index  int
+	engine engine.Engine
+}
+
+// keyspace возвращает базу index без блокировки - для восстановления из WAL и кода, уже держащего Database.mu
+func (d *Database) keyspace(index int) *keyspace {
+	return &keyspace{Database: d, index: index, engine: d.engines[index]}
+}
+
+// acquire возвращает базу index под блокировкой чтения. release можно вызывать повторно
+func (d *Database) acquire(index int) (*keyspace, func()) {
+	d.mu.RLock()
+
+	released := false
+	return d.keyspace(index), func() {
+		if !released {
+			released = true
+			d.mu.RUnlock()
+		}
+	}
+}
+
+// Len возвращает число ключей во всех базах
+func (d *Database) Len() int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.totalKeys()
+}
+
+// totalKeys считает ключи во всех базах. Вызывается под Database.mu
+func (d *Database) totalKeys() int {
+	total := 0
+	for _, engine := range d.engines {
+		total += engine.Len()
+	}
+	return total
+}
+
+// selectDatabase - SELECT index, переключает базу сессии
+func (d *Database) selectDatabase(session *network.Session, arg string) (network.Response, error) {
+	index, err := d.databaseIndex(arg)
+	if err != nil {
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+
+	session.DB = index
+	return network.OKResponse(), nil
+}
+
+func (d *Database) databaseIndex(arg string) (int, error) {
+	index, err := strconv.Atoi(arg)
+	if err != nil || index < 0 || index >= len(d.engines) {
+		return 0, fmt.Errorf("database index must be an integer from 0 to %d", len(d.engines)-1)
+	}
+	return index, nil
+}
+
+// rewriteDatabases выполняет FLUSHDB, FLUSHALL и SWAPDB. Блокировка записи ждет завершения команд над данными,
+// поэтому в WAL команда оказывается после всех записей, которые она затирает
+func (d *Database) rewriteDatabases(session *network.Session, query compute.Query, timings *Timings) (network.Response, error) {
+	index := 0
+	if query.CommandId == compute.FlushDBCommandId {
+		index = session.DB
+	}
+	if query.CommandId == compute.SwapDBCommandId {
+		if _, _, err := d.swapIndexes(query.Args); err != nil {
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	walStart := time.Now()
+	err := d.wal.Append(compute.EncodeRecord(index, query))
+	timings.Wal = time.Since(walStart)
+	if err != nil {
+		err = fmt.Errorf("command storing failed: %w", err)
+		return network.ErrorResponse(network.StatusStoreError, err), err
+	}
+
+	if err := d.applyDatabases(index, query); err != nil {
+		return network.ErrorResponse(network.StatusInternalError, err), err
+	}
+
+	return network.OKResponse(), nil
+}
+
+// applyDatabases применяет FLUSHDB к базе index, FLUSHALL и SWAPDB - ко всем базам. Вызывается под Database.mu
+// или при восстановлении из WAL
+func (d *Database) applyDatabases(index int, query compute.Query) error {
+	switch query.CommandId {
+	case compute.FlushDBCommandId:
+		d.engines[index].Flush()
+	case compute.FlushAllCommandId:
+		for _, engine := range d.engines {
+			engine.Flush()
+		}
+	case compute.SwapDBCommandId:
+		first, second, err := d.swapIndexes(query.Args)
+		if err != nil {
+			return err
+		}
+		// Движки меняются вместе с очередями BLPOP: ожидающие остаются при своих данных
+		d.engines[first], d.engines[second] = d.engines[second], d.engines[first]
+	default:
+		return fmt.Errorf("unknown command: %v", query.CommandId)
+	}
+	return nil
+}
+
+func (d *Database) swapIndexes(args []string) (int, int, error) {
+	first, err := d.databaseIndex(args[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	second, err := d.databaseIndex(args[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return first, second, nil
+}
@@ -0,0 +1,303 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// listWrite выполняет LPUSH, RPUSH, LPOP и RPOP. Как и у хешей, запись в WAL идет под блокировкой ключа
+func (d *keyspace) listWrite(query compute.Query, timings *Timings) (network.Response, error) {
+	key := query.Args[0]
+	commit := d.walCommit(timings)
+
+	switch query.CommandId {
+	case compute.LPushCommandId, compute.RPushCommandId:
+		length, err := d.pushList(query, commit)
+		if err != nil {
+			return typedErrorResponse(err), err
+		}
+
+		event := KeyEventLPush
+		if query.CommandId == compute.RPushCommandId {
+			event = KeyEventRPush
+		}
+		d.notify(true, key, event)
+		return network.ValueResponse(strconv.Itoa(length)), nil
+	default:
+		value, popped, _, err := d.popList(query, commit, false)
+		if err != nil {
+			return typedErrorResponse(err), err
+		}
+		if !popped {
+			return network.NotFoundResponse(), nil
+		}
+
+		event := KeyEventLPop
+		if query.CommandId == compute.RPopCommandId {
+			event = KeyEventRPop
+		}
+		d.notify(true, key, event)
+		return network.ValueResponse(value), nil
+	}
+}
+
+// pushList добавляет элементы и возвращает длину списка с учетом элементов, сразу выданных ожидающим BLPOP.
+// Ожидающие бывают только у пустого списка, поэтому выданные элементы не меняют данные, и в WAL пишется
+// RPUSH оставшихся. commit == nil при восстановлении из WAL
+func (d *keyspace) pushList(query compute.Query, commit func(compute.Query) error) (int, error) {
+	key, items := query.Args[0], query.Args[1:]
+	if query.CommandId == compute.LPushCommandId {
+		// LPUSH a b c добавляет элементы в голову по одному, поэтому список начинается с c b a
+		items = slices.Clone(items)
+		slices.Reverse(items)
+	}
+
+	waiters := d.engine.Waiters()
+	length := 0
+	err := d.engine.Update(key, func(value engine.Value) (engine.Value, error) {
+		if err := checkType(value, engine.TypeList); err != nil {
+			return value, err
+		}
+		length = len(value.List) + len(items)
+
+		record := query
+		served := min(waiters.Count(key), len(items))
+		if served > 0 {
+			record = compute.Query{CommandId: compute.RPushCommandId, Args: append([]string{key}, items[served:]...)}
+		}
+
+		if commit != nil && len(record.Args) > 1 {
+			if err := commit(record); err != nil {
+				return value, err
+			}
+		}
+
+		items = waiters.Serve(key, items)
+		if query.CommandId == compute.LPushCommandId {
+			value.List = append(items, value.List...)
+		} else {
+			value.List = append(value.List, items...)
+		}
+
+		if len(value.List) == 0 {
+			return engine.Value{}, nil
+		}
+		value.Type = engine.TypeList
+		return value, nil
+	})
+
+	return length, err
+}
+
+// popList снимает элемент с головы (LPOP, BLPOP) или хвоста (RPOP) списка. Если список пуст и wait,
+// сессия встает в очередь ожидающих ключа под той же блокировкой, поэтому добавление между проверкой
+// и регистрацией не теряется
+func (d *keyspace) popList(query compute.Query, commit func(compute.Query) error, wait bool) (string, bool, *engine.Waiter, error) {
+	key := query.Args[0]
+	fromTail := query.CommandId == compute.RPopCommandId
+
+	var element string
+	var popped bool
+	var waiter *engine.Waiter
+	err := d.engine.Update(key, func(value engine.Value) (engine.Value, error) {
+		if err := checkType(value, engine.TypeList); err != nil {
+			return value, err
+		}
+
+		if len(value.List) == 0 {
+			if wait {
+				waiter = d.engine.Waiters().Add(key)
+			}
+			return value, nil
+		}
+
+		if commit != nil {
+			// BLPOP восстанавливается как LPOP
+			record := compute.Query{CommandId: compute.LPopCommandId, Args: []string{key}}
+			if fromTail {
+				record.CommandId = compute.RPopCommandId
+			}
+			if err := commit(record); err != nil {
+				return value, err
+			}
+		}
+
+		if fromTail {
+			element, value.List = value.List[len(value.List)-1], value.List[:len(value.List)-1]
+		} else {
+			element, value.List = value.List[0], value.List[1:]
+		}
+		popped = true
+
+		if len(value.List) == 0 {
+			return engine.Value{}, nil
+		}
+		return value, nil
+	})
+
+	return element, popped, waiter, err
+}
+
+// blpop - BLPOP key timeout. Ждет элемент не дольше timeout секунд (0 - без ограничения) или до закрытия сессии.
+// По истечении ожидания возвращает NotFound. Перед ожиданием отпускает базу через release, чтобы не задерживать
+// FLUSHDB, FLUSHALL и SWAPDB: ожидающий остается в очереди движка и переезжает вместе с ним при SWAPDB
+func (d *keyspace) blpop(session *network.Session, query compute.Query, exec *execution, release func()) (network.Response, error) {
+	key := query.Args[0]
+
+	timeout, err := parseBlockTimeout(query.Args[1])
+	if err != nil {
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+
+	value, popped, waiter, err := d.popList(query, d.walCommit(&exec.timings), true)
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+	if popped {
+		d.notify(true, key, KeyEventLPop)
+		return network.ValueResponse(value), nil
+	}
+
+	release()
+
+	// Время ожидания не учитывается в журнале медленных запросов
+	blockStart := time.Now()
+	defer func() {
+		exec.blocked = time.Since(blockStart)
+	}()
+
+	stopWatch := session.Block()
+	defer stopWatch()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case value := <-waiter.C:
+		return d.deliver(session, key, value, exec)
+	case <-expired:
+	case <-session.Context().Done():
+	}
+
+	// Отмена идет под блокировкой ключа: если добавление успело выдать элемент, он уже лежит в канале
+	removed := false
+	_ = d.engine.View(key, func(engine.Value) error {
+		removed = d.engine.Waiters().Remove(waiter)
+		return nil
+	})
+	if !removed {
+		return d.deliver(session, key, <-waiter.C, exec)
+	}
+
+	if err := session.Context().Err(); err != nil {
+		err = fmt.Errorf("blocking wait interrupted: %w", err)
+		return network.ErrorResponse(network.StatusInternalError, err), err
+	}
+	return network.NotFoundResponse(), nil
+}
+
+// deliver отвечает BLPOP выданным элементом. Если сессия уже закрыта, ответ до клиента не дойдет,
+// и элемент возвращается в голову списка
+func (d *keyspace) deliver(session *network.Session, key, value string, exec *execution) (network.Response, error) {
+	if err := session.Context().Err(); err != nil {
+		if err := d.requeue(key, value, &exec.timings); err != nil {
+			return network.ErrorResponse(network.StatusStoreError, err), err
+		}
+		err = fmt.Errorf("blocking wait interrupted: %w", err)
+		return network.ErrorResponse(network.StatusInternalError, err), err
+	}
+
+	d.notify(true, key, KeyEventLPop)
+	return network.ValueResponse(value), nil
+}
+
+// requeue возвращает элемент в голову списка через LPUSH: выданный элемент в WAL не попадал, поэтому
+// LPUSH пишется в WAL как обычное добавление и может сразу достаться следующему ожидающему. База берется заново,
+// так как блокировка отпущена перед ожиданием, а SWAPDB мог перенести движок под другой номер
+func (d *keyspace) requeue(key, value string, timings *Timings) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	index := slices.Index(d.engines, d.engine)
+	if index < 0 {
+		return errors.New("database of the blocked command no longer exists")
+	}
+
+	ks := d.keyspace(index)
+	_, err := ks.pushList(compute.Query{CommandId: compute.LPushCommandId, Args: []string{key, value}}, ks.walCommit(timings))
+	if err == nil {
+		ks.notify(true, key, KeyEventLPush)
+	}
+	return err
+}
+
+func parseBlockTimeout(value string) (time.Duration, error) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 || math.IsNaN(seconds) || seconds > math.MaxInt64/float64(time.Second) {
+		return 0, errors.New("timeout must be a non-negative number of seconds")
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// listRead выполняет LRANGE и LLEN
+func (d *keyspace) listRead(query compute.Query) (network.Response, error) {
+	var start, stop int
+	if query.CommandId == compute.LRangeCommandId {
+		var err error
+		start, err = strconv.Atoi(query.Args[1])
+		if err == nil {
+			stop, err = strconv.Atoi(query.Args[2])
+		}
+		if err != nil {
+			err = errors.New("start and stop must be integers")
+			return network.ErrorResponse(network.StatusInvalidArgument, err), err
+		}
+	}
+
+	var response network.Response
+	err := d.engine.View(query.Args[0], func(value engine.Value) error {
+		if err := checkType(value, engine.TypeList); err != nil {
+			return err
+		}
+
+		if query.CommandId == compute.LLenCommandId {
+			response = network.ValueResponse(strconv.Itoa(len(value.List)))
+			return nil
+		}
+
+		response = network.ValuesResponse(toValues(listRange(value.List, start, stop)))
+		return nil
+	})
+
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+	return response, nil
+}
+
+// listRange возвращает копию элементов с start по stop включительно. Отрицательные индексы считаются от конца
+func listRange(list []string, start, stop int) []string {
+	if start < 0 {
+		start = max(len(list)+start, 0)
+	}
+	if stop < 0 {
+		stop = len(list) + stop
+	}
+	stop = min(stop, len(list)-1)
+
+	if start > stop {
+		return nil
+	}
+	return slices.Clone(list[start : stop+1])
+}
@@ -0,0 +1,128 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/network"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultMonitorBufferSize = 1024
+
+// Monitors рассылает выполненные запросы сессиям в режиме MONITOR. В отличие от Broker, медленный
+// монитор не отключается: не поместившиеся в буфер строки отбрасываются, а их число сообщается
+// монитору, как только в буфере появится место
+type Monitors struct {
+	mu         sync.RWMutex
+	monitors   map[*Monitor]struct{}
+	count      atomic.Int64
+	dropped    atomic.Uint64
+	bufferSize int
+}
+
+func NewMonitors(bufferSize int) *Monitors {
+	if bufferSize <= 0 {
+		bufferSize = defaultMonitorBufferSize
+	}
+
+	return &Monitors{
+		monitors:   make(map[*Monitor]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+// Monitor - поток запросов одной сессии, реализует network.PushSource
+type Monitor struct {
+	messages chan network.Response
+	done     chan struct{}
+	once     sync.Once
+	// pending - число строк, отброшенных с момента последнего отчета
+	pending atomic.Uint64
+}
+
+func (m *Monitor) Messages() <-chan network.Response {
+	return m.messages
+}
+
+func (m *Monitor) Done() <-chan struct{} {
+	return m.done
+}
+
+// Err всегда nil: монитор закрывается только вместе с сессией
+func (m *Monitor) Err() error {
+	return nil
+}
+
+// send отправляет строку без блокировки. Перед ней отправляется отчет об отброшенных строках, если они были
+func (m *Monitor) send(message network.Response) bool {
+	if pending := m.pending.Swap(0); pending > 0 {
+		select {
+		case m.messages <- network.MonitorResponse("", fmt.Sprintf("dropped %d", pending)):
+		default:
+			m.pending.Add(pending + 1)
+			return false
+		}
+	}
+
+	select {
+	case m.messages <- message:
+		return true
+	default:
+		m.pending.Add(1)
+		return false
+	}
+}
+
+func (m *Monitors) Add() *Monitor {
+	monitor := &Monitor{
+		messages: make(chan network.Response, m.bufferSize),
+		done:     make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	m.monitors[monitor] = struct{}{}
+	m.count.Store(int64(len(m.monitors)))
+	m.mu.Unlock()
+
+	return monitor
+}
+
+func (m *Monitors) Remove(monitor *Monitor) {
+	m.mu.Lock()
+	delete(m.monitors, monitor)
+	m.count.Store(int64(len(m.monitors)))
+	m.mu.Unlock()
+
+	monitor.once.Do(func() {
+		close(monitor.done)
+	})
+}
+
+// Feed отправляет выполненный запрос всем мониторам. Без мониторов не делает ничего, кроме чтения счетчика
+func (m *Monitors) Feed(remoteAddr, query string, status network.StatusCode) {
+	if m.count.Load() == 0 {
+		return
+	}
+
+	line := fmt.Sprintf("%s %s %s", time.Now().UTC().Format(time.RFC3339Nano), status, network.RedactQuery(query))
+	message := network.MonitorResponse(remoteAddr, line)
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for monitor := range m.monitors {
+		if !monitor.send(message) {
+			m.dropped.Add(1)
+		}
+	}
+}
+
+// Count возвращает число мониторов, Dropped - число строк, отброшенных с момента запуска
+func (m *Monitors) Count() int {
+	return int(m.count.Load())
+}
+
+func (m *Monitors) Dropped() uint64 {
+	return m.dropped.Load()
+}
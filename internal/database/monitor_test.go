@@ -0,0 +1,71 @@
+//go:build unit
+
+package database_test
+
+import (
+	"concurrency_hw/internal/database"
+	"concurrency_hw/internal/database/network"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestMonitors(t *testing.T) {
+	t.Run("Feed all monitors", func(t *testing.T) {
+		monitors := database.NewMonitors(10)
+		first := monitors.Add()
+		second := monitors.Add()
+
+		monitors.Feed("127.0.0.1:5000", "AUTH admin secret", network.StatusOK)
+
+		for _, monitor := range []*database.Monitor{first, second} {
+			message := <-monitor.Messages()
+			assert.Equal(t, network.StatusMonitor, message.Status)
+			assert.Equal(t, "127.0.0.1:5000", message.Channel)
+			require.NotNil(t, message.Value)
+			assert.True(t, strings.HasSuffix(*message.Value, " ok AUTH admin ***"), *message.Value)
+		}
+	})
+
+	t.Run("Slow monitor drops lines and reports them", func(t *testing.T) {
+		monitors := database.NewMonitors(2)
+		monitor := monitors.Add()
+
+		for range 5 {
+			monitors.Feed("127.0.0.1:5000", "GET a", network.StatusNotFound)
+		}
+		assert.Equal(t, uint64(3), monitors.Dropped())
+
+		<-monitor.Messages()
+		<-monitor.Messages()
+
+		monitors.Feed("127.0.0.1:5000", "GET b", network.StatusOK)
+
+		report := <-monitor.Messages()
+		assert.Equal(t, network.MonitorResponse("", "dropped 3"), report)
+
+		message := <-monitor.Messages()
+		require.NotNil(t, message.Value)
+		assert.True(t, strings.HasSuffix(*message.Value, " ok GET b"))
+
+		select {
+		case <-monitor.Done():
+			t.Fatal("slow monitor must not be disconnected")
+		default:
+		}
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		monitors := database.NewMonitors(10)
+		monitor := monitors.Add()
+		assert.Equal(t, 1, monitors.Count())
+
+		monitors.Remove(monitor)
+		assert.Equal(t, 0, monitors.Count())
+		<-monitor.Done()
+
+		monitors.Feed("127.0.0.1:5000", "GET a", network.StatusOK)
+		assert.Empty(t, monitor.Messages())
+	})
+}
@@ -1,9 +1,13 @@
 package network
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
-	"io"
 	"net"
+	"strings"
+	"time"
 )
 
 const (
@@ -11,27 +15,126 @@ const (
 )
 
 type TCPClient struct {
-	conn net.Conn
+	conn     net.Conn
+	reader   *bufio.Reader
+	encoding Encoding
+	// pushed - сообщения подписок, пришедшие во время ожидания ответа на запрос
+	pushed []Response
 }
 
-func NewTCPClient(address string) (*TCPClient, error) {
+func NewTCPClient(address string, encoding Encoding) (*TCPClient, error) {
 	conn, err := net.Dial("tcp", address)
 	if err != nil {
 		return nil, err
 	}
 
-	return &TCPClient{
-		conn: conn,
-	}, nil
+	client := &TCPClient{
+		conn:     conn,
+		reader:   bufio.NewReaderSize(conn, ReadBufferSize),
+		encoding: encoding,
+	}
+
+	if err := client.hello(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func (c *TCPClient) hello() error {
+	response, err := c.Query(HelloCommandToken + " " + c.encoding.String())
+	if err != nil {
+		return fmt.Errorf("cannot negotiate encoding: %w", err)
+	}
+
+	if response.IsError() {
+		return fmt.Errorf("cannot negotiate encoding: %s", response.Error)
+	}
+
+	return nil
 }
 
 func (c *TCPClient) Execute(queryString string) ([]byte, error) {
-	_, err := c.conn.Write([]byte(queryString))
+	_, err := c.conn.Write([]byte(terminateQuery(queryString)))
 	if err != nil {
 		return nil, fmt.Errorf("cannot send query to server: %w", err)
 	}
 
-	return readResponse(c.conn)
+	raw, _, err := c.readReply()
+	return raw, err
+}
+
+// Query выполняет запрос и декодирует ответ в кодировке, выбранной при подключении
+func (c *TCPClient) Query(queryString string) (Response, error) {
+	_, err := c.conn.Write([]byte(terminateQuery(queryString)))
+	if err != nil {
+		return Response{}, fmt.Errorf("cannot send query to server: %w", err)
+	}
+
+	_, response, err := c.readReply()
+	return response, err
+}
+
+// Receive возвращает следующее сообщение подписки, блокируясь до его прихода
+func (c *TCPClient) Receive() (Response, error) {
+	if len(c.pushed) > 0 {
+		message := c.pushed[0]
+		c.pushed = c.pushed[1:]
+		return message, nil
+	}
+
+	raw, err := c.readResponse()
+	if err != nil {
+		return Response{}, err
+	}
+
+	return c.decode(raw)
+}
+
+// Pipeline отправляет запросы, не дожидаясь ответов, и возвращает ответы в том же порядке. Запросы пишутся
+// параллельно с чтением ответов: иначе на большом пакете сервер блокируется на записи ответов в заполненный
+// буфер сокета, а клиент - на записи запросов
+func (c *TCPClient) Pipeline(queries []string) ([]Response, error) {
+	written := make(chan error, 1)
+	go func() {
+		writer := bufio.NewWriter(c.conn)
+		for _, query := range queries {
+			if _, err := writer.WriteString(terminateQuery(query)); err != nil {
+				break
+			}
+		}
+		err := writer.Flush()
+		if err != nil {
+			// Ответов на неотправленные запросы не будет: прерываем чтение
+			_ = c.conn.SetReadDeadline(time.Now())
+		}
+		written <- err
+	}()
+
+	responses := make([]Response, 0, len(queries))
+	for range queries {
+		_, response, err := c.readReply()
+		if err != nil {
+			// Соединение неисправно: прерываем запись, если она еще не завершилась
+			_ = c.conn.SetWriteDeadline(time.Now())
+			if writeErr := <-written; writeErr != nil {
+				return responses, fmt.Errorf("cannot send queries to server: %w", writeErr)
+			}
+			return responses, err
+		}
+
+		responses = append(responses, response)
+	}
+
+	if err := <-written; err != nil {
+		return responses, fmt.Errorf("cannot send queries to server: %w", err)
+	}
+	return responses, nil
+}
+
+func (c *TCPClient) Encoding() Encoding {
+	return c.encoding
 }
 
 func (c *TCPClient) Disconnect() error {
@@ -42,22 +145,45 @@ func (c *TCPClient) Disconnect() error {
 	return nil
 }
 
-func readResponse(conn net.Conn) ([]byte, error) {
-	var buf []byte
-	tmp := make([]byte, ReadBufferSize)
+func (c *TCPClient) decode(raw []byte) (Response, error) {
+	if len(raw) == 0 {
+		return Response{}, errors.New("empty response from server")
+	}
+
+	return DecodeResponse(c.encoding, raw)
+}
 
+// readReply читает ответ на запрос, откладывая пришедшие перед ним сообщения подписок
+func (c *TCPClient) readReply() ([]byte, Response, error) {
 	for {
-		n, err := conn.Read(tmp)
+		raw, err := c.readResponse()
 		if err != nil {
-			if err == io.EOF {
-				buf = append(buf, tmp[:n]...)
-				return buf, nil
-			}
-			return nil, err
+			return nil, Response{}, err
 		}
-		buf = append(buf, tmp[:n]...)
-		if n < ReadBufferSize {
-			return buf, nil
+
+		response, err := c.decode(raw)
+		if err != nil {
+			return nil, Response{}, err
 		}
+
+		if !response.IsPush() {
+			return raw, response, nil
+		}
+
+		c.pushed = append(c.pushed, response)
+	}
+}
+
+func (c *TCPClient) readResponse() ([]byte, error) {
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		return nil, err
 	}
+
+	return bytes.TrimSuffix(line, []byte("\n")), nil
+}
+
+// Запросы разделяются переводом строки, поэтому запрос не может содержать его внутри
+func terminateQuery(queryString string) string {
+	return strings.TrimRight(queryString, "\r\n") + "\n"
 }
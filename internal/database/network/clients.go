@@ -0,0 +1,120 @@
+package network
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientInfo - снимок состояния клиентского соединения для CLIENT LIST
+type ClientInfo struct {
+	ID           uint64
+	RemoteAddr   string
+	CreatedAt    time.Time
+	LastActivity time.Time
+	// LastCommand - только имя команды, аргументы не сохраняются, чтобы не светить значения и пароли
+	LastCommand string
+}
+
+type client struct {
+	info ClientInfo
+	kill func()
+}
+
+// ClientRegistry - реестр открытых соединений TCPServer
+type ClientRegistry struct {
+	mu      sync.RWMutex
+	clients map[uint64]*client
+	lastID  uint64
+}
+
+func NewClientRegistry() *ClientRegistry {
+	return &ClientRegistry{
+		clients: make(map[uint64]*client),
+	}
+}
+
+func (r *ClientRegistry) register(remoteAddr string, kill func()) uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastID++
+	now := time.Now()
+	r.clients[r.lastID] = &client{
+		info: ClientInfo{
+			ID:           r.lastID,
+			RemoteAddr:   remoteAddr,
+			CreatedAt:    now,
+			LastActivity: now,
+		},
+		kill: kill,
+	}
+
+	return r.lastID
+}
+
+func (r *ClientRegistry) touch(id uint64, query string) {
+	command, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if c, exists := r.clients[id]; exists {
+		c.info.LastActivity = time.Now()
+		c.info.LastCommand = command
+	}
+}
+
+func (r *ClientRegistry) unregister(id uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.clients, id)
+}
+
+// List возвращает открытые соединения в порядке подключения
+func (r *ClientRegistry) List() []ClientInfo {
+	r.mu.RLock()
+	infos := make([]ClientInfo, 0, len(r.clients))
+	for _, c := range r.clients {
+		infos = append(infos, c.info)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(infos, func(a, b ClientInfo) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	return infos
+}
+
+// Kill закрывает соединение и прерывает блокирующую команду сессии. Возвращает false, если соединения с таким id нет
+func (r *ClientRegistry) Kill(id uint64) bool {
+	r.mu.RLock()
+	c, exists := r.clients[id]
+	r.mu.RUnlock()
+
+	if !exists {
+		return false
+	}
+
+	c.kill()
+	return true
+}
+
+// Count возвращает число открытых соединений, Total - число соединений с момента запуска
+func (r *ClientRegistry) Count() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return len(r.clients)
+}
+
+func (r *ClientRegistry) Total() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.lastID
+}
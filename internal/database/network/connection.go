@@ -0,0 +1,96 @@
+package network
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// connection - клиентское соединение tcp-сервера. Ответы на запросы и сообщения подписчику пишутся
+// из разных горутин, поэтому запись в буфер защищена мьютексом
+type connection struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	session  *Session
+	mu       sync.Mutex
+	writer   *bufio.Writer
+	encoding Encoding
+	// pumped - источник, сообщения которого пересылает pump; stopPump останавливает эту пересылку
+	pumped   PushSource
+	stopPump chan struct{}
+	// writeTimeout ограничивает каждую запись в соединение, чтобы клиент, не читающий ответы, не блокировал сервер
+	writeTimeout time.Duration
+	messageLimit *messageLimit
+}
+
+func newConnection(ctx context.Context, conn net.Conn, readBufferSize int, writeTimeout time.Duration, messageLimit *messageLimit) *connection {
+	return &connection{
+		conn:         conn,
+		reader:       bufio.NewReaderSize(conn, readBufferSize),
+		writer:       bufio.NewWriter(conn),
+		session:      NewSessionContext(ctx, conn.RemoteAddr().String()),
+		writeTimeout: writeTimeout,
+		messageLimit: messageLimit,
+	}
+}
+
+func (c *connection) setEncoding(encoding Encoding) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.encoding = encoding
+}
+
+func (c *connection) write(response Response, flush bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	encoded, sent, err := c.messageLimit.encode(c.encoding, response)
+	if err != nil {
+		return err
+	}
+	if response.IsPush() && !sent.IsPush() {
+		return fmt.Errorf("%w of %d bytes", errPushTooLarge, c.messageLimit.maxBytes)
+	}
+
+	// Запись в буфер может сбросить его в соединение, поэтому дедлайн нужен и здесь
+	if err := c.setWriteDeadline(); err != nil {
+		return err
+	}
+
+	if _, err := c.writer.Write(append(encoded, '\n')); err != nil {
+		return err
+	}
+
+	if flush {
+		return c.writer.Flush()
+	}
+
+	return nil
+}
+
+func (c *connection) flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.writer.Buffered() == 0 {
+		return nil
+	}
+
+	if err := c.setWriteDeadline(); err != nil {
+		return err
+	}
+
+	return c.writer.Flush()
+}
+
+func (c *connection) setWriteDeadline() error {
+	if c.writeTimeout <= 0 {
+		return nil
+	}
+
+	return c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+}
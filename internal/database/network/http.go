@@ -0,0 +1,297 @@
+package network
+
+import (
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/compute"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+)
+
+var httpStatuses = map[StatusCode]int{
+	StatusOK:              http.StatusOK,
+	StatusNotFound:        http.StatusNotFound,
+	StatusParseError:      http.StatusBadRequest,
+	StatusUnknownCommand:  http.StatusBadRequest,
+	StatusInvalidArgument: http.StatusBadRequest,
+	StatusNotInteger:      http.StatusUnprocessableEntity,
+	StatusOverflow:        http.StatusUnprocessableEntity,
+	StatusUnsupported:     http.StatusNotImplemented,
+	StatusWrongType:       http.StatusConflict,
+	StatusMessageTooLarge: http.StatusRequestEntityTooLarge,
+	StatusNoConnections:   http.StatusServiceUnavailable,
+	StatusStoreError:      http.StatusInternalServerError,
+	StatusInternalError:   http.StatusInternalServerError,
+	StatusUnauthenticated: http.StatusUnauthorized,
+	StatusForbidden:       http.StatusForbidden,
+	StatusThrottled:       http.StatusTooManyRequests,
+	StatusTimeout:         http.StatusServiceUnavailable,
+}
+
+type connectionBucketKey struct{}
+
+type HTTPQueryRequest struct {
+	Query string `json:"query"`
+}
+
+// HTTPServer - http/json шлюз к той же функции обработки запросов, что и у TCPServer
+type HTTPServer struct {
+	logger         *zap.Logger
+	conf           *config.NetworkConfig
+	requestHandler RequestHandler
+	limiter        *ConnectionLimiter
+	rateLimiter    *RateLimiter
+	messageLimit   *messageLimit
+}
+
+func NewHTTPServer(
+	logger *zap.Logger,
+	conf *config.NetworkConfig,
+	limiter *ConnectionLimiter,
+	rateLimiter *RateLimiter,
+	requestHandler RequestHandler,
+) (*HTTPServer, error) {
+	messageLimit, err := newMessageLimit(conf.MaxMessageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTTPServer{
+		logger:         logger,
+		conf:           conf,
+		requestHandler: requestHandler,
+		limiter:        limiter,
+		rateLimiter:    rateLimiter,
+		messageLimit:   messageLimit,
+	}, nil
+}
+
+func (s *HTTPServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/keys/{key}", s.handleGet)
+	mux.HandleFunc("PUT /v1/keys/{key}", s.handleSet)
+	mux.HandleFunc("DELETE /v1/keys/{key}", s.handleDel)
+	mux.HandleFunc("POST /v1/query", s.handleQuery)
+
+	return mux
+}
+
+func (s *HTTPServer) Run(ctx context.Context) error {
+	listener, err := net.Listen("tcp", s.conf.HTTPAddress)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:      s.Handler(),
+		IdleTimeout:  s.conf.IdleTimeout,
+		ReadTimeout:  s.conf.ReadTimeout,
+		WriteTimeout: s.conf.WriteTimeout,
+		ConnContext: func(ctx context.Context, _ net.Conn) context.Context {
+			return context.WithValue(ctx, connectionBucketKey{}, s.rateLimiter.NewConnectionBucket())
+		},
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.logger.Info("shutting down http server")
+		if err := server.Close(); err != nil {
+			s.logger.Error("failed to close http server", zap.Error(err))
+		}
+	}()
+
+	s.logger.Info("http gateway listening on " + listener.Addr().String())
+
+	err = server.Serve(&limitedListener{Listener: listener, limiter: s.limiter, logger: s.logger})
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
+
+func (s *HTTPServer) handleGet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if !s.validToken(w, "key", key) {
+		return
+	}
+
+	s.execute(w, r, compute.Query{CommandId: compute.GetCommandId, Args: []string{key}}.String())
+}
+
+func (s *HTTPServer) handleSet(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if !s.validToken(w, "key", key) {
+		return
+	}
+
+	body, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	s.execute(w, r, compute.Query{CommandId: compute.SetCommandId, Args: []string{key, string(body)}}.String())
+}
+
+func (s *HTTPServer) handleDel(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	if !s.validToken(w, "key", key) {
+		return
+	}
+
+	s.execute(w, r, compute.Query{CommandId: compute.DelCommandId, Args: []string{key}}.String())
+}
+
+func (s *HTTPServer) handleQuery(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	var request HTTPQueryRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		s.write(w, ErrorResponse(StatusInvalidArgument, fmt.Errorf("cannot decode request body: %w", err)))
+		return
+	}
+
+	s.execute(w, r, request.Query)
+}
+
+func (s *HTTPServer) execute(w http.ResponseWriter, r *http.Request, query string) {
+	if int64(len(query)) > s.messageLimit.maxBytes {
+		s.write(w, s.messageLimit.rejectRequest())
+		return
+	}
+
+	session := NewSessionContext(r.Context(), r.RemoteAddr)
+	defer session.Close()
+
+	// Каждый http-запрос - отдельная сессия, учетные данные передаются через basic auth
+	if name, password, ok := r.BasicAuth(); ok {
+		auth := compute.Query{CommandId: compute.AuthCommandId, Args: []string{name, password}}
+		response, err := s.requestHandler(session, auth.String())
+		if err != nil || response.IsError() {
+			w.Header().Set("WWW-Authenticate", `Basic realm="concurrency_hw"`)
+			s.write(w, response)
+			return
+		}
+	}
+
+	bucket, _ := r.Context().Value(connectionBucketKey{}).(*TokenBucket)
+	if err := s.rateLimiter.Wait(r.Context(), bucket, session); err != nil {
+		s.logger.Warn("request throttled", zap.String("remote_addr", session.RemoteAddr), zap.Error(err))
+		s.write(w, ErrorResponse(StatusThrottled, err))
+		return
+	}
+
+	response, err := s.requestHandler(session, query)
+	if err != nil {
+		s.logger.Error("failed to handle http request",
+			zap.String("request", RedactQuery(query)),
+			zap.Stringer("status", response.Status),
+			zap.Error(err),
+		)
+	}
+
+	if response.Status == StatusUnauthenticated {
+		w.Header().Set("WWW-Authenticate", `Basic realm="concurrency_hw"`)
+	}
+
+	s.write(w, response)
+}
+
+func (s *HTTPServer) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.messageLimit.maxBytes))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			s.write(w, s.messageLimit.rejectRequest())
+		} else {
+			s.write(w, ErrorResponse(StatusInvalidArgument, fmt.Errorf("cannot read request body: %w", err)))
+		}
+		return nil, false
+	}
+
+	return body, true
+}
+
+// Ключи и значения попадают в запрос через compute.Quote, поэтому допустимы любые символы, кроме пустого ключа
+func (s *HTTPServer) validToken(w http.ResponseWriter, name, token string) bool {
+	if token == "" {
+		s.write(w, ErrorResponse(StatusInvalidArgument, fmt.Errorf("%s must be non-empty", name)))
+		return false
+	}
+
+	return true
+}
+
+func (s *HTTPServer) write(w http.ResponseWriter, response Response) {
+	encoded, response, err := s.messageLimit.encode(JSONEncoding, response)
+	if err != nil {
+		s.logger.Error("failed to encode http response", zap.Error(err))
+		encoded, response = []byte(`{"status":"internal_error"}`), Response{Status: StatusInternalError}
+	}
+
+	status, exists := httpStatuses[response.Status]
+	if !exists {
+		status = http.StatusInternalServerError
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(append(encoded, '\n')); err != nil {
+		s.logger.Error("failed to write http response", zap.Error(err))
+	}
+}
+
+// Oversized возвращает число запросов и ответов, отклоненных из-за max_message_size
+func (s *HTTPServer) Oversized() map[string]uint64 {
+	return s.messageLimit.stats()
+}
+
+// limitedListener делит лимит соединений с TCPServer, лишние соединения получают 503
+type limitedListener struct {
+	net.Listener
+	limiter *ConnectionLimiter
+	logger  *zap.Logger
+}
+
+func (l *limitedListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		if l.limiter.Acquire() {
+			return &limitedConn{Conn: conn, limiter: l.limiter}, nil
+		}
+
+		body, _ := EncodeResponse(JSONEncoding, ErrorResponse(StatusNoConnections, errors.New("no connections available")))
+		_, err = fmt.Fprintf(conn, "HTTP/1.1 503 Service Unavailable\r\nContent-Type: application/json\r\nContent-Length: %d\r\nConnection: close\r\n\r\n%s\n",
+			len(body)+1, body)
+		if err != nil {
+			l.logger.Error("failed to write response", zap.Error(err))
+		}
+		if err := conn.Close(); err != nil {
+			l.logger.Error("failed to close connection", zap.Error(err))
+		}
+	}
+}
+
+type limitedConn struct {
+	net.Conn
+	limiter *ConnectionLimiter
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(c.limiter.Release)
+	return c.Conn.Close()
+}
@@ -0,0 +1,128 @@
+//go:build unit
+
+package network_test
+
+import (
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPServer(t *testing.T) {
+	var queries []string
+	storage := map[string]string{}
+
+	handler := func(_ *network.Session, query string) (network.Response, error) {
+		queries = append(queries, query)
+		tokens, err := compute.Tokenize(query)
+		switch {
+		case err != nil:
+			return network.ErrorResponse(network.StatusParseError, err), err
+		case len(tokens) == 3 && tokens[0] == "SET":
+			storage[tokens[1]] = tokens[2]
+			return network.OKResponse(), nil
+		case len(tokens) == 2 && tokens[0] == "GET":
+			value, exists := storage[tokens[1]]
+			if !exists {
+				return network.NotFoundResponse(), nil
+			}
+			return network.ValueResponse(value), nil
+		case len(tokens) == 2 && tokens[0] == "DEL":
+			delete(storage, tokens[1])
+			return network.OKResponse(), nil
+		default:
+			err := fmt.Errorf("invalid query: %s", query)
+			return network.ErrorResponse(network.StatusParseError, err), err
+		}
+	}
+
+	conf := &config.NetworkConfig{
+		MaxMessageSize: "64b",
+		MaxConnections: 1,
+		RateLimit:      config.RateLimitConfig{Mode: network.RateLimitModeReject},
+	}
+	rateLimiter, err := network.NewRateLimiter(conf.RateLimit)
+	require.NoError(t, err)
+
+	server, err := network.NewHTTPServer(zaptest.NewLogger(t), conf, network.NewConnectionLimiter(1), rateLimiter, handler)
+	require.NoError(t, err)
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	do := func(method, path, body string) (int, network.Response) {
+		req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+
+		var decoded network.Response
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&decoded))
+		return resp.StatusCode, decoded
+	}
+
+	t.Run("PUT then GET", func(t *testing.T) {
+		status, resp := do(http.MethodPut, "/v1/keys/name", "value")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, network.StatusOK, resp.Status)
+
+		status, resp = do(http.MethodGet, "/v1/keys/name", "")
+		assert.Equal(t, http.StatusOK, status)
+		require.NotNil(t, resp.Value)
+		assert.Equal(t, "value", *resp.Value)
+	})
+
+	t.Run("DELETE", func(t *testing.T) {
+		status, _ := do(http.MethodDelete, "/v1/keys/name", "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, "DEL name", queries[len(queries)-1])
+
+		status, resp := do(http.MethodGet, "/v1/keys/name", "")
+		assert.Equal(t, http.StatusNotFound, status)
+		assert.Equal(t, network.StatusNotFound, resp.Status)
+		assert.Nil(t, resp.Value)
+	})
+
+	t.Run("Raw query", func(t *testing.T) {
+		status, resp := do(http.MethodPost, "/v1/query", `{"query":"SET a b"}`)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, network.StatusOK, resp.Status)
+		assert.Equal(t, "b", storage["a"])
+	})
+
+	t.Run("Parse error", func(t *testing.T) {
+		status, resp := do(http.MethodPost, "/v1/query", `{"query":"SET a"}`)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, network.StatusParseError, resp.Status)
+		assert.NotEmpty(t, resp.Error)
+	})
+
+	t.Run("Value with whitespace", func(t *testing.T) {
+		status, _ := do(http.MethodPut, "/v1/keys/name", "two words\n")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, `SET name "two words\n"`, queries[len(queries)-1])
+
+		status, resp := do(http.MethodGet, "/v1/keys/name", "")
+		assert.Equal(t, http.StatusOK, status)
+		require.NotNil(t, resp.Value)
+		assert.Equal(t, "two words\n", *resp.Value)
+	})
+
+	t.Run("Body exceeds max message size", func(t *testing.T) {
+		status, resp := do(http.MethodPut, "/v1/keys/name", strings.Repeat("v", 65))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, status)
+		assert.Equal(t, network.StatusMessageTooLarge, resp.Status)
+		assert.NotEmpty(t, resp.Error)
+	})
+}
@@ -0,0 +1,35 @@
+package network
+
+import "sync/atomic"
+
+// ConnectionLimiter - общий для tcp и http лимит одновременных соединений
+type ConnectionLimiter struct {
+	maxConnections int64
+	active         atomic.Int64
+}
+
+func NewConnectionLimiter(maxConnections int) *ConnectionLimiter {
+	return &ConnectionLimiter{
+		maxConnections: int64(maxConnections),
+	}
+}
+
+func (l *ConnectionLimiter) Acquire() bool {
+	for {
+		active := l.active.Load()
+		if active >= l.maxConnections {
+			return false
+		}
+		if l.active.CompareAndSwap(active, active+1) {
+			return true
+		}
+	}
+}
+
+func (l *ConnectionLimiter) Release() {
+	l.active.Add(-1)
+}
+
+func (l *ConnectionLimiter) Active() int64 {
+	return l.active.Load()
+}
@@ -0,0 +1,207 @@
+package network
+
+import (
+	"concurrency_hw/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	RateLimitModeReject = "reject"
+	RateLimitModeDelay  = "delay"
+
+	ScopeConnection = "connection"
+	ScopeUser       = "user"
+	ScopeGlobal     = "global"
+)
+
+var ErrThrottled = errors.New("rate limit exceeded")
+
+// TokenBucket - классический token bucket, время передается снаружи для тестируемости
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func NewTokenBucket(rate float64, burst int, now time.Time) *TokenBucket {
+	capacity := math.Max(float64(burst), 1)
+	return &TokenBucket{
+		rate:   rate,
+		burst:  capacity,
+		tokens: capacity,
+		last:   now,
+	}
+}
+
+// Take забирает токен. Если токена нет, возвращает время ожидания до его появления;
+// если ожидание больше maxWait, токен не забирается и возвращается false
+func (b *TokenBucket) Take(now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.After(b.last) {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+
+	wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxWait {
+		return wait, false
+	}
+
+	// Уходим в минус: следующий запрос будет ждать дольше
+	b.tokens--
+	return wait, true
+}
+
+// Refund возвращает токен, забранный Take для запроса, который не был выполнен
+func (b *TokenBucket) Refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+type ThrottleStats struct {
+	Delayed  uint64
+	Rejected uint64
+}
+
+type throttleCounters struct {
+	delayed  atomic.Uint64
+	rejected atomic.Uint64
+}
+
+type RateLimiter struct {
+	conf     config.RateLimitConfig
+	global   *TokenBucket
+	users    map[string]*TokenBucket
+	usersMu  sync.Mutex
+	counters map[string]*throttleCounters
+	now      func() time.Time
+}
+
+func NewRateLimiter(conf config.RateLimitConfig) (*RateLimiter, error) {
+	if conf.Mode != RateLimitModeReject && conf.Mode != RateLimitModeDelay {
+		return nil, fmt.Errorf("unknown rate limit mode: %s", conf.Mode)
+	}
+
+	limiter := &RateLimiter{
+		conf:  conf,
+		users: make(map[string]*TokenBucket),
+		counters: map[string]*throttleCounters{
+			ScopeConnection: {},
+			ScopeUser:       {},
+			ScopeGlobal:     {},
+		},
+		now: time.Now,
+	}
+
+	if conf.GlobalRate > 0 {
+		limiter.global = NewTokenBucket(conf.GlobalRate, conf.GlobalBurst, limiter.now())
+	}
+
+	return limiter, nil
+}
+
+// NewConnectionBucket возвращает bucket для нового соединения или nil, если лимит на соединение выключен
+func (l *RateLimiter) NewConnectionBucket() *TokenBucket {
+	if l.conf.ConnectionRate <= 0 {
+		return nil
+	}
+	return NewTokenBucket(l.conf.ConnectionRate, l.conf.ConnectionBurst, l.now())
+}
+
+// Wait проверяет лимиты соединения, пользователя и глобальный. В режиме delay ждет токен, в режиме reject
+// возвращает ErrThrottled. При отказе токены, уже забранные у других лимитов, возвращаются
+func (l *RateLimiter) Wait(ctx context.Context, connection *TokenBucket, session *Session) error {
+	maxWait := time.Duration(0)
+	if l.conf.Mode == RateLimitModeDelay {
+		maxWait = l.conf.MaxDelay
+	}
+
+	var wait time.Duration
+	var taken []*TokenBucket
+	for _, scoped := range []struct {
+		scope  string
+		bucket *TokenBucket
+	}{
+		{ScopeConnection, connection},
+		{ScopeUser, l.userBucket(session)},
+		{ScopeGlobal, l.global},
+	} {
+		if scoped.bucket == nil {
+			continue
+		}
+
+		scopeWait, ok := scoped.bucket.Take(l.now(), maxWait)
+		if !ok {
+			for _, bucket := range taken {
+				bucket.Refund()
+			}
+			l.counters[scoped.scope].rejected.Add(1)
+			return fmt.Errorf("%w: %s", ErrThrottled, scoped.scope)
+		}
+		if scopeWait > 0 {
+			l.counters[scoped.scope].delayed.Add(1)
+			wait = max(wait, scopeWait)
+		}
+		taken = append(taken, scoped.bucket)
+	}
+
+	if wait == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *RateLimiter) Stats() map[string]ThrottleStats {
+	stats := make(map[string]ThrottleStats, len(l.counters))
+	for scope, counters := range l.counters {
+		stats[scope] = ThrottleStats{
+			Delayed:  counters.delayed.Load(),
+			Rejected: counters.rejected.Load(),
+		}
+	}
+	return stats
+}
+
+func (l *RateLimiter) userBucket(session *Session) *TokenBucket {
+	if l.conf.UserRate <= 0 || session == nil || session.User == nil {
+		return nil
+	}
+
+	l.usersMu.Lock()
+	defer l.usersMu.Unlock()
+
+	name := session.User.Name()
+	bucket, exists := l.users[name]
+	if !exists {
+		bucket = NewTokenBucket(l.conf.UserRate, l.conf.UserBurst, l.now())
+		l.users[name] = bucket
+	}
+
+	return bucket
+}
@@ -0,0 +1,110 @@
+//go:build unit
+
+package network_test
+
+import (
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/network"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Take(t *testing.T) {
+	now := time.Now()
+	bucket := network.NewTokenBucket(10, 2, now)
+
+	// Burst расходуется сразу
+	for range 2 {
+		wait, ok := bucket.Take(now, 0)
+		assert.True(t, ok)
+		assert.Zero(t, wait)
+	}
+
+	// Без ожидания токена нет
+	wait, ok := bucket.Take(now, 0)
+	assert.False(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	// С допустимым ожиданием токен выдается в долг
+	wait, ok = bucket.Take(now, time.Second)
+	assert.True(t, ok)
+	assert.Equal(t, 100*time.Millisecond, wait)
+
+	// Через 200ms долг погашен и накоплен новый токен
+	wait, ok = bucket.Take(now.Add(200*time.Millisecond), 0)
+	assert.True(t, ok)
+	assert.Zero(t, wait)
+}
+
+func TestRateLimiter_Reject(t *testing.T) {
+	limiter, err := network.NewRateLimiter(config.RateLimitConfig{
+		Mode:            network.RateLimitModeReject,
+		ConnectionRate:  1,
+		ConnectionBurst: 2,
+	})
+	require.NoError(t, err)
+
+	bucket := limiter.NewConnectionBucket()
+	session := network.NewSession("test")
+
+	require.NoError(t, limiter.Wait(context.Background(), bucket, session))
+	require.NoError(t, limiter.Wait(context.Background(), bucket, session))
+
+	err = limiter.Wait(context.Background(), bucket, session)
+	assert.ErrorIs(t, err, network.ErrThrottled)
+
+	// У другого соединения свой bucket
+	require.NoError(t, limiter.Wait(context.Background(), limiter.NewConnectionBucket(), session))
+
+	assert.Equal(t, network.ThrottleStats{Rejected: 1}, limiter.Stats()[network.ScopeConnection])
+}
+
+func TestRateLimiter_RejectRefunds(t *testing.T) {
+	limiter, err := network.NewRateLimiter(config.RateLimitConfig{
+		Mode:            network.RateLimitModeReject,
+		ConnectionRate:  0.001,
+		ConnectionBurst: 2,
+		GlobalRate:      0.001,
+		GlobalBurst:     1,
+	})
+	require.NoError(t, err)
+
+	bucket := limiter.NewConnectionBucket()
+	session := network.NewSession("test")
+
+	require.NoError(t, limiter.Wait(context.Background(), bucket, session))
+	err = limiter.Wait(context.Background(), bucket, session)
+	assert.ErrorIs(t, err, network.ErrThrottled)
+
+	// Отказ глобального лимита не расходует токен соединения
+	_, ok := bucket.Take(time.Now(), 0)
+	assert.True(t, ok)
+	assert.Equal(t, network.ThrottleStats{Rejected: 1}, limiter.Stats()[network.ScopeGlobal])
+}
+
+func TestRateLimiter_Delay(t *testing.T) {
+	limiter, err := network.NewRateLimiter(config.RateLimitConfig{
+		Mode:        network.RateLimitModeDelay,
+		MaxDelay:    time.Second,
+		GlobalRate:  50,
+		GlobalBurst: 1,
+	})
+	require.NoError(t, err)
+
+	session := network.NewSession("test")
+
+	start := time.Now()
+	require.NoError(t, limiter.Wait(context.Background(), nil, session))
+	require.NoError(t, limiter.Wait(context.Background(), nil, session))
+	assert.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+
+	assert.Equal(t, network.ThrottleStats{Delayed: 1}, limiter.Stats()[network.ScopeGlobal])
+}
+
+func TestNewRateLimiter_UnknownMode(t *testing.T) {
+	_, err := network.NewRateLimiter(config.RateLimitConfig{Mode: "drop"})
+	assert.Error(t, err)
+}
@@ -1,10 +1,301 @@
 package network
 
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type StatusCode uint8
+
+const (
+	StatusOK StatusCode = iota
+	StatusNotFound
+	StatusParseError
+	StatusUnknownCommand
+	StatusStoreError
+	StatusInvalidArgument
+	StatusMessageTooLarge
+	StatusNoConnections
+	StatusInternalError
+	StatusUnauthenticated
+	StatusForbidden
+	StatusThrottled
+	StatusSlowConsumer
+	// StatusMessage - не ответ на запрос, а сообщение, отправленное сервером подписчику
+	StatusMessage
+	// StatusEvent - событие изменения ключа для подписчика NOTIFY, в Channel ключ, в Value тип события
+	StatusEvent
+	StatusTimeout
+	// StatusMonitor - выполненный запрос для сессии MONITOR, в Channel адрес клиента,
+	// в Value "время статус запрос". Пустой Channel - отчет "dropped N" об отброшенных строках
+	StatusMonitor
+	// StatusNotInteger и StatusOverflow - ошибки INCR/DECR: значение не int64 или результат не помещается в int64
+	StatusNotInteger
+	StatusOverflow
+	// StatusUnsupported - команда не поддерживается настроенным движком
+	StatusUnsupported
+	// StatusWrongType - команда применена к ключу со значением другого типа
+	StatusWrongType
+)
+
+var statusNames = map[StatusCode]string{
+	StatusOK:              "ok",
+	StatusNotFound:        "not_found",
+	StatusParseError:      "parse_error",
+	StatusUnknownCommand:  "unknown_command",
+	StatusStoreError:      "store_error",
+	StatusInvalidArgument: "invalid_argument",
+	StatusMessageTooLarge: "message_too_large",
+	StatusNoConnections:   "no_connections",
+	StatusInternalError:   "internal_error",
+	StatusUnauthenticated: "unauthenticated",
+	StatusForbidden:       "forbidden",
+	StatusThrottled:       "throttled",
+	StatusSlowConsumer:    "slow_consumer",
+	StatusMessage:         "message",
+	StatusEvent:           "event",
+	StatusTimeout:         "timeout",
+	StatusMonitor:         "monitor",
+	StatusNotInteger:      "not_integer",
+	StatusOverflow:        "overflow",
+	StatusUnsupported:     "unsupported",
+	StatusWrongType:       "wrongtype",
+}
+
+func (c StatusCode) String() string {
+	if name, exists := statusNames[c]; exists {
+		return name
+	}
+	return fmt.Sprintf("status_%d", uint8(c))
+}
+
+func ParseStatusCode(name string) (StatusCode, error) {
+	for code, codeName := range statusNames {
+		if codeName == name {
+			return code, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status code: %s", name)
+}
+
+func (c StatusCode) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.String())
+}
+
+func (c *StatusCode) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+
+	code, err := ParseStatusCode(name)
+	if err != nil {
+		return err
+	}
+
+	*c = code
+	return nil
+}
+
+// Response - ответ сервера. Value == nil означает, что у команды нет значения, а пустая строка - что значение пустое.
+// Values - значения команд с несколькими ключами (MGET), nil в них - отсутствующий ключ
+type Response struct {
+	Status  StatusCode `json:"status"`
+	Channel string     `json:"channel,omitempty"`
+	Value   *string    `json:"value,omitempty"`
+	Values  []*string  `json:"values,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+func OKResponse() Response {
+	return Response{Status: StatusOK}
+}
+
+func ValueResponse(value string) Response {
+	return Response{Status: StatusOK, Value: &value}
+}
+
+func ValuesResponse(values []*string) Response {
+	return Response{Status: StatusOK, Values: values}
+}
+
+func NotFoundResponse() Response {
+	return Response{Status: StatusNotFound}
+}
+
+func MessageResponse(channel, payload string) Response {
+	return Response{Status: StatusMessage, Channel: channel, Value: &payload}
+}
+
+func EventResponse(key, event string) Response {
+	return Response{Status: StatusEvent, Channel: key, Value: &event}
+}
+
+func MonitorResponse(remoteAddr, line string) Response {
+	return Response{Status: StatusMonitor, Channel: remoteAddr, Value: &line}
+}
+
+func ErrorResponse(status StatusCode, err error) Response {
+	return Response{Status: status, Error: err.Error()}
+}
+
+func (r Response) IsError() bool {
+	return r.Status != StatusOK && r.Status != StatusNotFound && !r.IsPush()
+}
+
+func (r Response) IsPush() bool {
+	return r.Status == StatusMessage || r.Status == StatusEvent || r.Status == StatusMonitor
+}
+
+type Encoding uint8
+
+const (
+	TextEncoding Encoding = iota
+	JSONEncoding
+)
+
 const (
-	NoConnectionsAvailable = "[error] no connections available"
-	CannotParseQuery       = "[error] cannot parse query"
-	UnknownCommand         = "[error] unknown command: %v"
-	CommandStoreError      = "[error] command storing failed: %v"
-	SuccessCommand         = "[success]"
-	GetResult              = "[success] %v"
+	textEncodingName = "text"
+	jsonEncodingName = "json"
 )
+
+func (e Encoding) String() string {
+	if e == JSONEncoding {
+		return jsonEncodingName
+	}
+	return textEncodingName
+}
+
+func ParseEncoding(name string) (Encoding, error) {
+	switch strings.ToLower(name) {
+	case textEncodingName:
+		return TextEncoding, nil
+	case jsonEncodingName:
+		return JSONEncoding, nil
+	default:
+		return 0, fmt.Errorf("unknown encoding: %s", name)
+	}
+}
+
+// EncodeResponse кодирует ответ. Текстовый формат: "[status]", "[status] "quoted value"", "[status] error message",
+// "[status] ["value" nil ...]" для нескольких значений или для сообщений подписчику "[message] "channel" "payload"", "[event] "key" "event"" и "[monitor] "addr" "line""
+func EncodeResponse(encoding Encoding, response Response) ([]byte, error) {
+	if encoding == JSONEncoding {
+		return json.Marshal(response)
+	}
+
+	var builder strings.Builder
+	builder.WriteString("[" + response.Status.String() + "]")
+
+	if response.IsPush() {
+		builder.WriteString(" " + strconv.Quote(response.Channel))
+	}
+
+	switch {
+	case response.Value != nil:
+		builder.WriteString(" " + strconv.Quote(*response.Value))
+	case response.Values != nil:
+		builder.WriteString(" [")
+		for i, value := range response.Values {
+			if i > 0 {
+				builder.WriteByte(' ')
+			}
+			if value == nil {
+				builder.WriteString(nilValue)
+			} else {
+				builder.WriteString(strconv.Quote(*value))
+			}
+		}
+		builder.WriteByte(']')
+	case response.Error != "":
+		builder.WriteString(" " + strings.ReplaceAll(response.Error, "\n", " "))
+	}
+
+	return []byte(builder.String()), nil
+}
+
+func DecodeResponse(encoding Encoding, data []byte) (Response, error) {
+	var response Response
+
+	if encoding == JSONEncoding {
+		err := json.Unmarshal(data, &response)
+		return response, err
+	}
+
+	text := string(data)
+	end := strings.IndexByte(text, ']')
+	if !strings.HasPrefix(text, "[") || end < 0 {
+		return response, errors.New("malformed text response")
+	}
+
+	status, err := ParseStatusCode(text[1:end])
+	if err != nil {
+		return response, err
+	}
+	response.Status = status
+
+	payload := strings.TrimPrefix(text[end+1:], " ")
+	if payload == "" {
+		return response, nil
+	}
+
+	if response.IsError() {
+		response.Error = payload
+		return response, nil
+	}
+
+	if response.IsPush() {
+		quoted, err := strconv.QuotedPrefix(payload)
+		if err != nil {
+			return response, fmt.Errorf("malformed message channel: %w", err)
+		}
+
+		response.Channel, _ = strconv.Unquote(quoted)
+		payload = strings.TrimPrefix(payload[len(quoted):], " ")
+	}
+
+	if strings.HasPrefix(payload, "[") {
+		response.Values, err = decodeValues(payload)
+		return response, err
+	}
+
+	value, err := strconv.Unquote(payload)
+	if err != nil {
+		return response, fmt.Errorf("malformed response value: %w", err)
+	}
+	response.Value = &value
+
+	return response, nil
+}
+
+// nilValue - отсутствующее значение в текстовом списке значений
+const nilValue = "nil"
+
+func decodeValues(payload string) ([]*string, error) {
+	if !strings.HasSuffix(payload, "]") {
+		return nil, errors.New("malformed response values")
+	}
+
+	values := []*string{}
+	for rest := strings.TrimSpace(payload[1 : len(payload)-1]); rest != ""; rest = strings.TrimPrefix(rest, " ") {
+		if strings.HasPrefix(rest, nilValue) {
+			values = append(values, nil)
+			rest = rest[len(nilValue):]
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(rest)
+		if err != nil {
+			return nil, fmt.Errorf("malformed response values: %w", err)
+		}
+
+		value, _ := strconv.Unquote(quoted)
+		values = append(values, &value)
+		rest = rest[len(quoted):]
+	}
+
+	return values, nil
+}
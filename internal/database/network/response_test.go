@@ -0,0 +1,110 @@
+//go:build unit
+
+package network_test
+
+import (
+	"concurrency_hw/internal/database/network"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestEncodeResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		response network.Response
+		text     string
+		json     string
+	}{
+		{
+			name:     "OK without value",
+			response: network.OKResponse(),
+			text:     `[ok]`,
+			json:     `{"status":"ok"}`,
+		},
+		{
+			name:     "OK with value",
+			response: network.ValueResponse("value"),
+			text:     `[ok] "value"`,
+			json:     `{"status":"ok","value":"value"}`,
+		},
+		{
+			name:     "OK with empty value",
+			response: network.ValueResponse(""),
+			text:     `[ok] ""`,
+			json:     `{"status":"ok","value":""}`,
+		},
+		{
+			name:     "Not found",
+			response: network.NotFoundResponse(),
+			text:     `[not_found]`,
+			json:     `{"status":"not_found"}`,
+		},
+		{
+			name:     "Message",
+			response: network.MessageResponse("news", "hello world"),
+			text:     `[message] "news" "hello world"`,
+			json:     `{"status":"message","channel":"news","value":"hello world"}`,
+		},
+		{
+			name:     "Key event",
+			response: network.EventResponse("user:1", "set"),
+			text:     `[event] "user:1" "set"`,
+			json:     `{"status":"event","channel":"user:1","value":"set"}`,
+		},
+		{
+			name:     "Monitor",
+			response: network.MonitorResponse("127.0.0.1:5000", "2026-01-02T03:04:05Z ok GET a"),
+			text:     `[monitor] "127.0.0.1:5000" "2026-01-02T03:04:05Z ok GET a"`,
+			json:     `{"status":"monitor","channel":"127.0.0.1:5000","value":"2026-01-02T03:04:05Z ok GET a"}`,
+		},
+		{
+			name:     "Several values",
+			response: network.ValuesResponse([]*string{ptr("a b"), nil, ptr("")}),
+			text:     `[ok] ["a b" nil ""]`,
+			json:     `{"status":"ok","values":["a b",null,""]}`,
+		},
+		{
+			name:     "Error",
+			response: network.ErrorResponse(network.StatusParseError, errors.New("invalid count of arguments")),
+			text:     `[parse_error] invalid count of arguments`,
+			json:     `{"status":"parse_error","error":"invalid count of arguments"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := network.EncodeResponse(network.TextEncoding, tt.response)
+			require.NoError(t, err)
+			assert.Equal(t, tt.text, string(text))
+
+			jsonData, err := network.EncodeResponse(network.JSONEncoding, tt.response)
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.json, string(jsonData))
+
+			decoded, err := network.DecodeResponse(network.TextEncoding, text)
+			require.NoError(t, err)
+			assert.Equal(t, tt.response, decoded)
+
+			decoded, err = network.DecodeResponse(network.JSONEncoding, jsonData)
+			require.NoError(t, err)
+			assert.Equal(t, tt.response, decoded)
+		})
+	}
+}
+
+func TestDecodeResponse_Malformed(t *testing.T) {
+	_, err := network.DecodeResponse(network.TextEncoding, []byte("success"))
+	assert.Error(t, err)
+
+	_, err = network.DecodeResponse(network.TextEncoding, []byte("[unknown]"))
+	assert.Error(t, err)
+
+	_, err = network.DecodeResponse(network.JSONEncoding, []byte(`{"status":"unknown"}`))
+	assert.Error(t, err)
+}
+
+func ptr(value string) *string {
+	return &value
+}
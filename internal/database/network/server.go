@@ -1,37 +1,85 @@
 package network
 
 import (
+	"bufio"
 	"concurrency_hw/internal/config"
 	"context"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"io"
 	"net"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// HelloCommandToken - первая команда соединения, которой клиент выбирает кодировку ответов: HELLO text|json
+const HelloCommandToken = "HELLO"
+
+type RequestHandler func(session *Session, query string) (Response, error)
+
+// Виды таймаутов соединения
+const (
+	TimeoutIdle    = "idle"
+	TimeoutRead    = "read"
+	TimeoutWrite   = "write"
+	TimeoutHandler = "handler"
+)
+
+var errHandlerTimeout = errors.New("handler timeout exceeded")
+
 type TCPServer struct {
-	logger           *zap.Logger
-	conf             *config.NetworkConfig
-	requestHandler   func(string) (string, error)
-	connections      int
-	requestBytesSize int64
+	logger         *zap.Logger
+	conf           *config.NetworkConfig
+	requestHandler RequestHandler
+	limiter        *ConnectionLimiter
+	rateLimiter    *RateLimiter
+	clients        *ClientRegistry
+	messageLimit   *messageLimit
+	oversizePolicy string
+	timeouts       map[string]*atomic.Uint64
 }
 
 func NewTCPServer(
 	logger *zap.Logger,
 	conf *config.NetworkConfig,
-	requestHandler func(string) (string, error),
+	limiter *ConnectionLimiter,
+	rateLimiter *RateLimiter,
+	clients *ClientRegistry,
+	requestHandler RequestHandler,
 ) (*TCPServer, error) {
-	requestBytesSize, err := config.ParseSizeInBytes(conf.MaxMessageSize)
+	messageLimit, err := newMessageLimit(conf.MaxMessageSize)
 	if err != nil {
 		return nil, err
 	}
 
+	oversizePolicy := conf.OversizePolicy
+	switch oversizePolicy {
+	case "":
+		oversizePolicy = OversizePolicyDiscard
+	case OversizePolicyDiscard, OversizePolicyClose:
+	default:
+		return nil, fmt.Errorf("unknown oversize policy: %s", oversizePolicy)
+	}
+
 	return &TCPServer{
-		logger:           logger,
-		conf:             conf,
-		requestBytesSize: requestBytesSize,
-		requestHandler:   requestHandler,
-		connections:      0,
+		logger:         logger,
+		conf:           conf,
+		messageLimit:   messageLimit,
+		oversizePolicy: oversizePolicy,
+		requestHandler: requestHandler,
+		limiter:        limiter,
+		rateLimiter:    rateLimiter,
+		clients:        clients,
+		timeouts: map[string]*atomic.Uint64{
+			TimeoutIdle:    new(atomic.Uint64),
+			TimeoutRead:    new(atomic.Uint64),
+			TimeoutWrite:   new(atomic.Uint64),
+			TimeoutHandler: new(atomic.Uint64),
+		},
 	}, nil
 }
 
@@ -40,12 +88,26 @@ func (s *TCPServer) Run(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+
+	return s.Serve(ctx, listener)
+}
+
+func (s *TCPServer) Serve(ctx context.Context, listener net.Listener) error {
+	var handlers sync.WaitGroup
+	defer handlers.Wait()
+
 	defer func() {
-		if err := listener.Close(); err != nil {
+		if err := listener.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			s.logger.Error("failed to close listener", zap.Error(err))
 		}
 	}()
 
+	// Закрываем listener при остановке, чтобы разблокировать Accept
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
 	s.logger.Info("listening on port" + listener.Addr().String())
 
 	for {
@@ -56,32 +118,74 @@ func (s *TCPServer) Run(ctx context.Context) error {
 		default:
 			conn, err := listener.Accept()
 			if err != nil {
+				if ctx.Err() != nil {
+					s.logger.Info("shutting down tcp server")
+					return nil
+				}
 				s.logger.Error("failed to accept connection", zap.Error(err))
 				continue
 			}
 
-			if s.connections < s.conf.MaxConnections {
-				s.connections++
-
-				go s.handleConnection(ctx, conn)
+			if s.limiter.Acquire() {
+				handlers.Add(1)
+				go func() {
+					defer handlers.Done()
+					s.handleConnection(ctx, conn)
+				}()
 			} else {
-				s.response(conn, []byte(NoConnectionsAvailable))
+				if s.conf.WriteTimeout > 0 {
+					_ = conn.SetWriteDeadline(time.Now().Add(s.conf.WriteTimeout))
+				}
+				s.response(conn, TextEncoding, ErrorResponse(StatusNoConnections, errors.New("no connections available")))
+				if err := conn.Close(); err != nil {
+					s.logger.Error("failed to close connection", zap.Error(err))
+				}
 			}
 		}
 
 	}
 }
 
+// handleConnection читает запросы, разделенные переводом строки. Клиент может отправить несколько запросов
+// подряд, не дожидаясь ответов: они выполняются по порядку, а ответы отправляются одной пачкой,
+// когда во входном буфере не остается прочитанных запросов
 func (s *TCPServer) handleConnection(ctx context.Context, conn net.Conn) {
+	// Буфер вмещает запрос максимального размера вместе с переводом строки
+	c := newConnection(ctx, conn, int(s.messageLimit.maxBytes)+1, s.conf.WriteTimeout, s.messageLimit)
+
+	// При остановке сервера прерываем блокирующее чтение
+	stopClosing := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+
+	c.session.ID = s.clients.register(c.session.RemoteAddr, func() {
+		c.session.cancel()
+		_ = conn.Close()
+	})
+	c.session.watch = func() func() {
+		return s.watchClose(ctx, c)
+	}
+
 	defer func() {
+		stopClosing()
+		s.clients.unregister(c.session.ID)
+
 		if r := recover(); r != nil {
 			s.logger.Error("captured panic", zap.Any("panic", r))
 		}
 
+		if err := c.flush(); err != nil {
+			s.writeFailed(c, err)
+		}
+		c.session.Close()
+		s.startPump(c, nil)
 		s.CloseConnection(conn)
 	}()
 
-	request := make([]byte, s.requestBytesSize)
+	s.setKeepAlive(conn)
+
+	bucket := s.rateLimiter.NewConnectionBucket()
+	firstRequest := true
 
 	for {
 		select {
@@ -89,44 +193,361 @@ func (s *TCPServer) handleConnection(ctx context.Context, conn net.Conn) {
 			s.logger.Info("closing tcp connection")
 			return
 		default:
-			if s.conf.IdleTimeout > 0 {
-				if err := conn.SetReadDeadline(time.Now().Add(s.conf.IdleTimeout)); err != nil {
-					s.logger.Error("failed to set read deadline", zap.Error(err))
+			if c.reader.Buffered() == 0 {
+				if err := c.flush(); err != nil {
+					s.writeFailed(c, err)
+					return
+				}
+
+				// Подписчик может долго ничего не отправлять, поэтому idle-таймаут к нему не применяется
+				idleTimeout := s.conf.IdleTimeout
+				if c.session.PushSource() != nil {
+					idleTimeout = 0
+				}
+
+				if err := s.awaitRequest(ctx, c, idleTimeout); err != nil {
+					s.readFailed(ctx, c, TimeoutIdle, err)
+					return
 				}
 			}
-			count, err := conn.Read(request)
+
+			if err := s.setReadDeadline(ctx, conn, s.conf.ReadTimeout); err != nil {
+				s.logger.Error("failed to set read deadline", zap.Error(err))
+			}
+
+			line, err := c.reader.ReadSlice('\n')
+			if errors.Is(err, bufio.ErrBufferFull) {
+				if !s.rejectOversized(ctx, c) {
+					return
+				}
+				continue
+			}
 			if err != nil {
-				s.logger.Error("failed to read request", zap.Error(err))
+				s.readFailed(ctx, c, TimeoutRead, err)
 				return
 			}
 
-			command := string(request[:count])
-			response, err := s.requestHandler(command)
+			command := strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r")
+			s.clients.touch(c.session.ID, command)
+
+			if firstRequest {
+				firstRequest = false
+				if hello, ok := s.hello(command); ok {
+					c.setEncoding(hello.encoding)
+					s.write(c, hello.response)
+					continue
+				}
+			}
+
+			if err := s.rateLimiter.Wait(ctx, bucket, c.session); err != nil {
+				s.logger.Warn("request throttled", zap.String("remote_addr", c.session.RemoteAddr), zap.Error(err))
+				s.write(c, ErrorResponse(StatusThrottled, err))
+				continue
+			}
+
+			response, err := s.handle(c, command)
+			if errors.Is(err, errHandlerTimeout) {
+				// Обработчик продолжает работу с сессией, поэтому соединение дальше не обслуживается
+				s.write(c, response)
+				return
+			}
 
 			if err != nil {
 				s.logger.Error("failed to handle request",
-					zap.String("request", command),
-					zap.String("response", response),
+					zap.String("request", RedactQuery(command)),
+					zap.Stringer("status", response.Status),
 					zap.Error(err),
 				)
 			}
 
-			s.response(conn, []byte(response))
+			s.write(c, response)
+
+			if source := c.session.PushSource(); source != c.pumped {
+				s.startPump(c, source)
+			}
 		}
 	}
 }
 
+// rejectOversized отвечает ошибкой на запрос больше max_message_size. Остаток запроса пропускается,
+// чтобы его часть не выполнилась как отдельный запрос. Возвращает false, если соединение нужно закрыть
+func (s *TCPServer) rejectOversized(ctx context.Context, c *connection) bool {
+	response := s.messageLimit.rejectRequest()
+	s.logger.Warn("request exceeds max message size",
+		zap.String("remote_addr", c.session.RemoteAddr),
+		zap.Int64("max_message_size", s.messageLimit.maxBytes),
+	)
+
+	s.write(c, response)
+
+	if s.oversizePolicy == OversizePolicyClose {
+		return false
+	}
+
+	for {
+		_, err := c.reader.ReadSlice('\n')
+		if err == nil {
+			return true
+		}
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			s.readFailed(ctx, c, TimeoutRead, err)
+			return false
+		}
+	}
+}
+
+// Oversized возвращает число запросов и ответов, отклоненных из-за max_message_size
+func (s *TCPServer) Oversized() map[string]uint64 {
+	return s.messageLimit.stats()
+}
+
+// watchClose читает соединение, пока команда сессии ждет: EOF или ошибка чтения отменяют контекст сессии.
+// Прочитанное остается в буфере и разбирается как следующие запросы. stop прерывает чтение дедлайном
+// и дожидается его завершения, после чего дедлайн снова выставляет цикл чтения запросов
+func (s *TCPServer) watchClose(ctx context.Context, c *connection) (stop func()) {
+	// Запросы, уже лежащие в буфере, пропускаем: Peek ждет хотя бы одного байта сверх них
+	next := c.reader.Buffered() + 1
+	if next > c.reader.Size() {
+		return func() {}
+	}
+
+	if err := s.setReadDeadline(ctx, c.conn, 0); err != nil {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := c.reader.Peek(next); err != nil && !errors.Is(err, os.ErrDeadlineExceeded) {
+			c.session.cancel()
+		}
+	}()
+
+	return func() {
+		_ = c.conn.SetReadDeadline(time.Now())
+		<-done
+	}
+}
+
+// awaitRequest ждет начала следующего запроса не дольше timeout
+func (s *TCPServer) awaitRequest(ctx context.Context, c *connection, timeout time.Duration) error {
+	if err := s.setReadDeadline(ctx, c.conn, timeout); err != nil {
+		return err
+	}
+
+	_, err := c.reader.Peek(1)
+	return err
+}
+
+// setReadDeadline выставляет дедлайн чтения, нулевой timeout снимает его. Если сервер уже останавливается,
+// дедлайн остается в прошлом, чтобы чтение сразу прервалось
+func (s *TCPServer) setReadDeadline(ctx context.Context, conn net.Conn, timeout time.Duration) error {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return conn.SetReadDeadline(time.Now())
+	}
+
+	return nil
+}
+
+// handle выполняет запрос с ограничением HandlerTimeout. Если обработчик не уложился в таймаут,
+// возвращается ошибка errHandlerTimeout
+func (s *TCPServer) handle(c *connection, command string) (Response, error) {
+	if s.conf.HandlerTimeout <= 0 {
+		return s.requestHandler(c.session, command)
+	}
+
+	type result struct {
+		response Response
+		err      error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		response, err := s.requestHandler(c.session, command)
+		done <- result{response: response, err: err}
+	}()
+
+	timer := time.NewTimer(s.conf.HandlerTimeout)
+	defer timer.Stop()
+
+	select {
+	case r := <-done:
+		return r.response, r.err
+	case <-timer.C:
+		err := fmt.Errorf("%w: request was not handled in %s", errHandlerTimeout, s.conf.HandlerTimeout)
+		s.timedOut(c, TimeoutHandler, err, zap.String("request", RedactQuery(command)))
+		return ErrorResponse(StatusTimeout, err), err
+	}
+}
+
+func (s *TCPServer) setKeepAlive(conn net.Conn) {
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+
+	keepAlive := s.conf.KeepAlive
+	err := tcpConn.SetKeepAliveConfig(net.KeepAliveConfig{
+		Enable:   keepAlive.Enabled,
+		Idle:     keepAlive.Idle,
+		Interval: keepAlive.Interval,
+		Count:    keepAlive.Count,
+	})
+	if err != nil {
+		s.logger.Warn("failed to configure tcp keepalive", zap.Error(err))
+	}
+}
+
+func (s *TCPServer) readFailed(ctx context.Context, c *connection, kind string, err error) {
+	switch {
+	case ctx.Err() != nil || errors.Is(err, net.ErrClosed):
+		s.logger.Info("closing tcp connection")
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		s.timedOut(c, kind, err)
+	case errors.Is(err, io.EOF):
+		s.logger.Debug("client closed tcp connection", zap.String("remote_addr", c.session.RemoteAddr))
+	default:
+		s.logger.Error("failed to read request", zap.Error(err))
+	}
+}
+
+func (s *TCPServer) writeFailed(c *connection, err error) {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		s.timedOut(c, TimeoutWrite, err)
+		return
+	}
+
+	s.logger.Error("failed to write response", zap.Error(err))
+}
+
+// timedOut логирует и учитывает таймаут соединения
+func (s *TCPServer) timedOut(c *connection, kind string, err error, fields ...zap.Field) {
+	s.timeouts[kind].Add(1)
+
+	s.logger.Warn("connection timeout", append([]zap.Field{
+		zap.String("kind", kind),
+		zap.String("remote_addr", c.session.RemoteAddr),
+		zap.Error(err),
+	}, fields...)...)
+}
+
+// Timeouts возвращает число таймаутов по видам с момента запуска
+func (s *TCPServer) Timeouts() map[string]uint64 {
+	timeouts := make(map[string]uint64, len(s.timeouts))
+	for kind, counter := range s.timeouts {
+		timeouts[kind] = counter.Load()
+	}
+	return timeouts
+}
+
+// startPump переключает пересылку сообщений на новый источник сессии (SUBSCRIBE, затем MONITOR)
+func (s *TCPServer) startPump(c *connection, source PushSource) {
+	if c.stopPump != nil {
+		close(c.stopPump)
+		c.stopPump = nil
+	}
+
+	c.pumped = source
+	if source == nil {
+		return
+	}
+
+	c.stopPump = make(chan struct{})
+	go s.pump(c, source, c.stopPump)
+}
+
+// pump пересылает клиенту сообщения подписок. Если источник отключает клиента, соединение закрывается
+func (s *TCPServer) pump(c *connection, source PushSource, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case message := <-source.Messages():
+			if err := c.write(message, true); err != nil {
+				if errors.Is(err, errPushTooLarge) {
+					s.logger.Warn("disconnecting subscriber",
+						zap.String("remote_addr", c.session.RemoteAddr),
+						zap.Error(err),
+					)
+					_ = c.write(ErrorResponse(StatusMessageTooLarge, err), true)
+				} else {
+					s.writeFailed(c, err)
+				}
+				_ = c.conn.Close()
+				return
+			}
+		case <-source.Done():
+			if err := source.Err(); err != nil {
+				s.logger.Warn("disconnecting subscriber",
+					zap.String("remote_addr", c.session.RemoteAddr),
+					zap.Error(err),
+				)
+				_ = c.write(ErrorResponse(StatusSlowConsumer, err), true)
+				_ = c.conn.Close()
+			}
+			return
+		}
+	}
+}
+
+func (s *TCPServer) write(c *connection, response Response) {
+	if err := c.write(response, false); err != nil {
+		s.writeFailed(c, err)
+	}
+}
+
+type helloResult struct {
+	encoding Encoding
+	response Response
+}
+
+func (s *TCPServer) hello(command string) (helloResult, bool) {
+	tokens := strings.Fields(command)
+	if len(tokens) == 0 || tokens[0] != HelloCommandToken {
+		return helloResult{}, false
+	}
+
+	if len(tokens) != 2 {
+		return helloResult{
+			encoding: TextEncoding,
+			response: ErrorResponse(StatusParseError, errors.New("invalid count of arguments")),
+		}, true
+	}
+
+	encoding, err := ParseEncoding(tokens[1])
+	if err != nil {
+		return helloResult{encoding: TextEncoding, response: ErrorResponse(StatusInvalidArgument, err)}, true
+	}
+
+	return helloResult{encoding: encoding, response: OKResponse()}, true
+}
+
 func (s *TCPServer) CloseConnection(conn net.Conn) {
 	if err := conn.Close(); err != nil {
 		s.logger.Error("failed to close connection", zap.Error(err))
 	}
-	s.connections--
+	s.limiter.Release()
 }
 
-func (s *TCPServer) response(conn net.Conn, response []byte) {
-	if _, err := conn.Write(response); err != nil {
+func (s *TCPServer) response(writer io.Writer, encoding Encoding, response Response) {
+	encoded, err := EncodeResponse(encoding, response)
+	if err != nil {
+		s.logger.Error("failed to encode response", zap.Error(err))
+		return
+	}
+
+	if _, err := writer.Write(append(encoded, '\n')); err != nil {
 		s.logger.Error("failed to write response",
-			zap.ByteString("response", response),
+			zap.ByteString("response", encoded),
 			zap.Error(err),
 		)
 	}
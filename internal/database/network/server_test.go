@@ -0,0 +1,554 @@
+//go:build unit
+
+package network_test
+
+import (
+	"bufio"
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/network"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer поднимает TCPServer на свободном порту с заданным обработчиком
+func startTestServer(t *testing.T, conf *config.NetworkConfig, handler network.RequestHandler) string {
+	t.Helper()
+
+	_, address := startServer(t, conf, handler)
+	return address
+}
+
+func startServer(t *testing.T, conf *config.NetworkConfig, handler network.RequestHandler) (*network.TCPServer, string) {
+	t.Helper()
+
+	return startServerWithClients(t, conf, network.NewClientRegistry(), handler)
+}
+
+func startServerWithClients(
+	t *testing.T,
+	conf *config.NetworkConfig,
+	clients *network.ClientRegistry,
+	handler network.RequestHandler,
+) (*network.TCPServer, string) {
+	t.Helper()
+
+	if conf.MaxMessageSize == "" {
+		conf.MaxMessageSize = "4KB"
+	}
+	if conf.MaxConnections == 0 {
+		conf.MaxConnections = 10
+	}
+	if conf.RateLimit.Mode == "" {
+		conf.RateLimit.Mode = network.RateLimitModeReject
+	}
+
+	rateLimiter, err := network.NewRateLimiter(conf.RateLimit)
+	require.NoError(t, err)
+
+	server, err := network.NewTCPServer(zaptest.NewLogger(t), conf,
+		network.NewConnectionLimiter(conf.MaxConnections), rateLimiter, clients, handler)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, server.Serve(ctx, listener))
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return server, listener.Addr().String()
+}
+
+func echoHandler(_ *network.Session, query string) (network.Response, error) {
+	return network.ValueResponse(query), nil
+}
+
+func TestTCPServer_Pipelining(t *testing.T) {
+	address := startTestServer(t, &config.NetworkConfig{}, echoHandler)
+
+	t.Run("Client pipeline", func(t *testing.T) {
+		client, err := network.NewTCPClient(address, network.JSONEncoding)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, client.Disconnect())
+		}()
+
+		queries := make([]string, 100)
+		for i := range queries {
+			queries[i] = fmt.Sprintf("GET key%d", i)
+		}
+
+		responses, err := client.Pipeline(queries)
+		require.NoError(t, err)
+		require.Len(t, responses, len(queries))
+
+		for i, response := range responses {
+			assert.Equal(t, network.ValueResponse(queries[i]), response)
+		}
+	})
+
+	t.Run("Pipeline larger than socket buffers", func(t *testing.T) {
+		address := startTestServer(t, &config.NetworkConfig{WriteTimeout: time.Second}, echoHandler)
+
+		client, err := network.NewTCPClient(address, network.JSONEncoding)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, client.Disconnect())
+		}()
+
+		// ~10MB в каждую сторону: запись пакета целиком до чтения ответов упирается в буферы сокета
+		value := strings.Repeat("v", 3000)
+		queries := make([]string, 3000)
+		for i := range queries {
+			queries[i] = fmt.Sprintf("SET key%d %s", i, value)
+		}
+
+		responses, err := client.Pipeline(queries)
+		require.NoError(t, err)
+		require.Len(t, responses, len(queries))
+		assert.Equal(t, network.ValueResponse(queries[len(queries)-1]), responses[len(responses)-1])
+	})
+
+	t.Run("Several requests in one read", func(t *testing.T) {
+		conn, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, conn.Close())
+		}()
+
+		_, err = conn.Write([]byte("GET a\nGET b\r\nGET c\n"))
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		reader := bufio.NewReader(conn)
+		for _, want := range []string{`[ok] "GET a"`, `[ok] "GET b"`, `[ok] "GET c"`} {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			assert.Equal(t, want, strings.TrimSuffix(line, "\n"))
+		}
+	})
+
+	t.Run("Request split across writes", func(t *testing.T) {
+		conn, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		defer func() {
+			require.NoError(t, conn.Close())
+		}()
+
+		_, err = conn.Write([]byte("GET wh"))
+		require.NoError(t, err)
+		time.Sleep(50 * time.Millisecond)
+		_, err = conn.Write([]byte("ole\n"))
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, `[ok] "GET whole"`+"\n", line)
+	})
+}
+
+type testPushSource struct {
+	messages chan network.Response
+	done     chan struct{}
+	err      error
+}
+
+func (s *testPushSource) Messages() <-chan network.Response { return s.messages }
+func (s *testPushSource) Done() <-chan struct{}             { return s.done }
+func (s *testPushSource) Err() error                        { return s.err }
+
+func TestTCPServer_Push(t *testing.T) {
+	source := &testPushSource{
+		messages: make(chan network.Response),
+		done:     make(chan struct{}),
+		err:      errors.New("subscriber buffer overflow"),
+	}
+	closed := make(chan struct{})
+
+	address := startTestServer(t, &config.NetworkConfig{}, func(session *network.Session, query string) (network.Response, error) {
+		if query == "SUBSCRIBE news" {
+			session.SetPushSource(source)
+			session.OnClose(func() { close(closed) })
+			return network.ValueResponse("1"), nil
+		}
+		return echoHandler(session, query)
+	})
+
+	client, err := network.NewTCPClient(address, network.JSONEncoding)
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	response, err := client.Query("SUBSCRIBE news")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("1"), response)
+
+	source.messages <- network.MessageResponse("news", "first")
+
+	// Сообщение, пришедшее во время ожидания ответа, не путается с ответом
+	source.messages <- network.MessageResponse("news", "second")
+	response, err = client.Query("PING")
+	require.NoError(t, err)
+	assert.Equal(t, network.ValueResponse("PING"), response)
+
+	for _, want := range []string{"first", "second"} {
+		message, err := client.Receive()
+		require.NoError(t, err)
+		assert.Equal(t, network.MessageResponse("news", want), message)
+	}
+
+	close(source.done)
+
+	message, err := client.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, network.StatusSlowConsumer, message.Status)
+
+	_, err = client.Receive()
+	assert.Error(t, err)
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("session was not closed")
+	}
+}
+
+func TestTCPServer_PushSourceSwitch(t *testing.T) {
+	subscriber := &testPushSource{messages: make(chan network.Response, 1), done: make(chan struct{})}
+	monitor := &testPushSource{messages: make(chan network.Response, 1), done: make(chan struct{})}
+
+	address := startTestServer(t, &config.NetworkConfig{}, func(session *network.Session, query string) (network.Response, error) {
+		switch query {
+		case "SUBSCRIBE news":
+			session.SetPushSource(subscriber)
+		case "MONITOR":
+			session.SetPushSource(monitor)
+		default:
+			return echoHandler(session, query)
+		}
+		return network.OKResponse(), nil
+	})
+
+	client, err := network.NewTCPClient(address, network.JSONEncoding)
+	require.NoError(t, err)
+	defer func() {
+		_ = client.Disconnect()
+	}()
+
+	for _, query := range []string{"SUBSCRIBE news", "MONITOR"} {
+		response, err := client.Query(query)
+		require.NoError(t, err)
+		assert.Equal(t, network.OKResponse(), response)
+	}
+
+	// Сообщения нового источника пересылаются, старого - нет
+	subscriber.messages <- network.MessageResponse("news", "stale")
+	monitor.messages <- network.MessageResponse("monitor", "GET a")
+
+	message, err := client.Receive()
+	require.NoError(t, err)
+	assert.Equal(t, network.MessageResponse("monitor", "GET a"), message)
+}
+
+func TestTCPServer_Timeouts(t *testing.T) {
+	dial := func(t *testing.T, address string) (net.Conn, *bufio.Reader) {
+		conn, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = conn.Close()
+		})
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+		return conn, bufio.NewReader(conn)
+	}
+
+	t.Run("Idle connection is closed", func(t *testing.T) {
+		server, address := startServer(t, &config.NetworkConfig{IdleTimeout: 50 * time.Millisecond}, echoHandler)
+		conn, reader := dial(t, address)
+
+		_, err := conn.Write([]byte("PING\n"))
+		require.NoError(t, err)
+		_, err = reader.ReadString('\n')
+		require.NoError(t, err)
+
+		_, err = reader.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, uint64(1), server.Timeouts()[network.TimeoutIdle])
+	})
+
+	t.Run("Incomplete request", func(t *testing.T) {
+		server, address := startServer(t, &config.NetworkConfig{ReadTimeout: 50 * time.Millisecond}, echoHandler)
+		conn, reader := dial(t, address)
+
+		_, err := conn.Write([]byte("GET ke"))
+		require.NoError(t, err)
+
+		_, err = reader.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, uint64(1), server.Timeouts()[network.TimeoutRead])
+		assert.Equal(t, uint64(0), server.Timeouts()[network.TimeoutIdle])
+	})
+
+	t.Run("Slow handler", func(t *testing.T) {
+		server, address := startServer(t, &config.NetworkConfig{HandlerTimeout: 50 * time.Millisecond},
+			func(session *network.Session, query string) (network.Response, error) {
+				if query == "SLOW" {
+					time.Sleep(200 * time.Millisecond)
+				}
+				return echoHandler(session, query)
+			})
+		conn, reader := dial(t, address)
+
+		_, err := conn.Write([]byte("FAST\nSLOW\nFAST\n"))
+		require.NoError(t, err)
+
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, `[ok] "FAST"`+"\n", line)
+
+		line, err = reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(line, "[timeout]"), line)
+
+		_, err = reader.ReadString('\n')
+		assert.ErrorIs(t, err, io.EOF)
+		assert.Equal(t, uint64(1), server.Timeouts()[network.TimeoutHandler])
+	})
+
+	t.Run("Client that does not read responses", func(t *testing.T) {
+		value := strings.Repeat("x", 64<<10)
+		server, address := startServer(t, &config.NetworkConfig{MaxMessageSize: "1MB", WriteTimeout: 50 * time.Millisecond},
+			func(_ *network.Session, _ string) (network.Response, error) {
+				return network.ValueResponse(value), nil
+			})
+		conn, _ := dial(t, address)
+
+		go func() {
+			for {
+				if _, err := conn.Write([]byte("GET key\n")); err != nil {
+					return
+				}
+			}
+		}()
+
+		assert.Eventually(t, func() bool {
+			return server.Timeouts()[network.TimeoutWrite] > 0
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestTCPServer_MaxMessageSize(t *testing.T) {
+	handler := func(_ *network.Session, query string) (network.Response, error) {
+		if value, found := strings.CutPrefix(query, "BIG "); found {
+			return network.ValueResponse(strings.Repeat("x", len(value)*100)), nil
+		}
+		return network.ValueResponse(strconv.Itoa(len(query))), nil
+	}
+
+	request := func(t *testing.T, address, payload string, count int) []string {
+		conn, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		defer conn.Close()
+		require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+		_, err = conn.Write([]byte(payload))
+		require.NoError(t, err)
+
+		reader := bufio.NewReader(conn)
+		lines := make([]string, 0, count)
+		for range count {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			lines = append(lines, strings.TrimSuffix(line, "\n"))
+		}
+		return lines
+	}
+
+	oversized := "SET key " + strings.Repeat("v", 100)
+
+	t.Run("Oversized request is discarded", func(t *testing.T) {
+		server, address := startServer(t, &config.NetworkConfig{MaxMessageSize: "64b"}, handler)
+
+		limit := "GET " + strings.Repeat("k", 60)
+		lines := request(t, address, "GET a\n"+oversized+"\n"+limit+"\nGET b\n", 4)
+
+		require.Len(t, lines, 4)
+		assert.Equal(t, `[ok] "5"`, lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "[message_too_large]"), lines[1])
+		assert.Equal(t, `[ok] "64"`, lines[2])
+		assert.Equal(t, `[ok] "5"`, lines[3])
+
+		assert.Equal(t, uint64(1), server.Oversized()[network.DirectionRequest])
+	})
+
+	t.Run("Oversized request closes the connection by policy", func(t *testing.T) {
+		server, address := startServer(t, &config.NetworkConfig{
+			MaxMessageSize: "64b",
+			OversizePolicy: network.OversizePolicyClose,
+		}, handler)
+
+		lines := request(t, address, oversized+"\nGET b\n", 2)
+
+		require.Len(t, lines, 1)
+		assert.True(t, strings.HasPrefix(lines[0], "[message_too_large]"), lines[0])
+		assert.Equal(t, uint64(1), server.Oversized()[network.DirectionRequest])
+	})
+
+	t.Run("Oversized response", func(t *testing.T) {
+		server, address := startServer(t, &config.NetworkConfig{MaxMessageSize: "64b"}, handler)
+
+		lines := request(t, address, "BIG x\nGET a\n", 2)
+
+		require.Len(t, lines, 2)
+		assert.True(t, strings.HasPrefix(lines[0], "[message_too_large] response exceeds"), lines[0])
+		assert.Equal(t, `[ok] "5"`, lines[1])
+		assert.Equal(t, uint64(1), server.Oversized()[network.DirectionResponse])
+	})
+
+	t.Run("Oversized subscription message disconnects the subscriber", func(t *testing.T) {
+		source := &testPushSource{messages: make(chan network.Response, 1), done: make(chan struct{})}
+		server, address := startServer(t, &config.NetworkConfig{MaxMessageSize: "64b"},
+			func(session *network.Session, query string) (network.Response, error) {
+				if query == "SUBSCRIBE news" {
+					session.SetPushSource(source)
+					return network.ValueResponse("1"), nil
+				}
+				return handler(session, query)
+			})
+
+		client, err := network.NewTCPClient(address, network.TextEncoding)
+		require.NoError(t, err)
+		defer func() {
+			_ = client.Disconnect()
+		}()
+
+		_, err = client.Query("SUBSCRIBE news")
+		require.NoError(t, err)
+
+		source.messages <- network.MessageResponse("news", strings.Repeat("x", 100))
+
+		// Вместо сообщения - последняя ошибка с причиной, после нее соединение закрывается
+		message, err := client.Receive()
+		require.NoError(t, err)
+		assert.Equal(t, network.StatusMessageTooLarge, message.Status)
+		assert.Contains(t, message.Error, "subscription message exceeds")
+
+		_, err = client.Receive()
+		assert.Error(t, err)
+		assert.Equal(t, uint64(1), server.Oversized()[network.DirectionResponse])
+	})
+}
+
+func TestTCPServer_BlockedCommandInterrupted(t *testing.T) {
+	interrupted := make(chan struct{}, 1)
+	clients := network.NewClientRegistry()
+	_, address := startServerWithClients(t, &config.NetworkConfig{}, clients,
+		func(session *network.Session, query string) (network.Response, error) {
+			stop := session.Block()
+			defer stop()
+
+			<-session.Context().Done()
+			interrupted <- struct{}{}
+			return network.ErrorResponse(network.StatusInternalError, session.Context().Err()), session.Context().Err()
+		})
+
+	block := func(t *testing.T) net.Conn {
+		conn, err := net.Dial("tcp", address)
+		require.NoError(t, err)
+		_, err = conn.Write([]byte("BLPOP jobs 0\n"))
+		require.NoError(t, err)
+
+		require.Eventually(t, func() bool {
+			infos := clients.List()
+			return len(infos) == 1 && infos[0].LastCommand == "BLPOP"
+		}, 5*time.Second, 5*time.Millisecond)
+		return conn
+	}
+
+	t.Run("Disconnect", func(t *testing.T) {
+		conn := block(t)
+		require.NoError(t, conn.Close())
+
+		select {
+		case <-interrupted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("blocked command is not interrupted by disconnect")
+		}
+		require.Eventually(t, func() bool {
+			return clients.Count() == 0
+		}, 5*time.Second, 5*time.Millisecond)
+	})
+
+	t.Run("Kill", func(t *testing.T) {
+		conn := block(t)
+		defer conn.Close()
+
+		require.True(t, clients.Kill(clients.List()[0].ID))
+
+		select {
+		case <-interrupted:
+		case <-time.After(5 * time.Second):
+			t.Fatal("blocked command is not interrupted by CLIENT KILL")
+		}
+	})
+}
+
+func TestTCPServer_ClientRegistry(t *testing.T) {
+	clients := network.NewClientRegistry()
+	_, address := startServerWithClients(t, &config.NetworkConfig{}, clients, echoHandler)
+
+	first, err := network.NewTCPClient(address, network.TextEncoding)
+	require.NoError(t, err)
+	defer first.Disconnect()
+
+	second, err := network.NewTCPClient(address, network.TextEncoding)
+	require.NoError(t, err)
+	defer second.Disconnect()
+
+	_, err = first.Query("GET secret")
+	require.NoError(t, err)
+
+	infos := clients.List()
+	require.Len(t, infos, 2)
+	assert.Less(t, infos[0].ID, infos[1].ID)
+	assert.Equal(t, "GET", infos[0].LastCommand)
+	assert.Equal(t, network.HelloCommandToken, infos[1].LastCommand)
+	assert.Equal(t, uint64(2), clients.Total())
+
+	t.Run("Kill", func(t *testing.T) {
+		require.True(t, clients.Kill(infos[0].ID))
+		assert.False(t, clients.Kill(100))
+
+		_, err := first.Query("PING")
+		assert.Error(t, err)
+
+		require.Eventually(t, func() bool {
+			return clients.Count() == 1
+		}, 5*time.Second, 5*time.Millisecond)
+
+		response, err := second.Query("PING")
+		require.NoError(t, err)
+		assert.Equal(t, network.ValueResponse("PING"), response)
+	})
+}
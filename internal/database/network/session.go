@@ -0,0 +1,116 @@
+package network
+
+import (
+	"concurrency_hw/internal/database/auth"
+	"context"
+	"strings"
+	"sync"
+)
+
+// PushSource - источник сообщений, которые сервер отправляет клиенту без запроса (pub/sub)
+type PushSource interface {
+	Messages() <-chan Response
+	// Done закрывается, когда источник отключает клиента, причина возвращается из Err
+	Done() <-chan struct{}
+	Err() error
+}
+
+// Session - состояние одного клиентского соединения
+type Session struct {
+	// ID - номер соединения в ClientRegistry, 0 для сессий вне TCPServer
+	ID         uint64
+	RemoteAddr string
+	User       *auth.User
+	// DB - номер базы, выбранной командой SELECT
+	DB int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	// watch - наблюдение сервера за разрывом соединения на время блокирующей команды, nil вне TCPServer
+	watch func() (stop func())
+
+	mu         sync.Mutex
+	pushSource PushSource
+	onClose    []func()
+	closed     bool
+}
+
+func NewSession(remoteAddr string) *Session {
+	return NewSessionContext(context.Background(), remoteAddr)
+}
+
+// NewSessionContext создает сессию, контекст которой отменяется вместе с ctx или при закрытии сессии
+func NewSessionContext(ctx context.Context, remoteAddr string) *Session {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Session{RemoteAddr: remoteAddr, ctx: ctx, cancel: cancel}
+}
+
+// Context отменяется при закрытии сессии или остановке сервера. По нему прерываются блокирующие команды
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+// Block вызывается командой перед ожиданием: пока не вызвана возвращенная stop, разрыв соединения
+// отменяет Context. Без этого разрыв замечается только после ответа, когда сервер снова читает запросы
+func (s *Session) Block() (stop func()) {
+	if s.watch == nil {
+		return func() {}
+	}
+	return s.watch()
+}
+
+func (s *Session) SetPushSource(source PushSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pushSource = source
+}
+
+func (s *Session) PushSource() PushSource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pushSource
+}
+
+// OnClose регистрирует освобождение ресурсов сессии при закрытии соединения
+func (s *Session) OnClose(f func()) {
+	s.mu.Lock()
+	if !s.closed {
+		s.onClose = append(s.onClose, f)
+		s.mu.Unlock()
+		return
+	}
+	s.mu.Unlock()
+
+	f()
+}
+
+func (s *Session) Close() {
+	s.cancel()
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	hooks := s.onClose
+	s.onClose = nil
+	s.mu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// RedactQuery скрывает пароль в AUTH-запросах перед логированием
+func RedactQuery(query string) string {
+	tokens := strings.Fields(query)
+	if len(tokens) > 0 && tokens[0] == "AUTH" {
+		if len(tokens) > 1 {
+			return "AUTH " + tokens[1] + " ***"
+		}
+	}
+	return query
+}
@@ -0,0 +1,68 @@
+package network
+
+import (
+	"concurrency_hw/internal/config"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// Политики обработки запроса больше max_message_size: discard пропускает остаток запроса до перевода строки
+// и продолжает обслуживать соединение, close закрывает соединение после ответа с ошибкой
+const (
+	OversizePolicyDiscard = "discard"
+	OversizePolicyClose   = "close"
+)
+
+// Направления сообщений, превысивших max_message_size
+const (
+	DirectionRequest  = "request"
+	DirectionResponse = "response"
+)
+
+// errPushTooLarge - сообщение подписки больше лимита. Ошибку вместо него клиент принял бы за ответ на свой
+// следующий запрос, поэтому подписчик получает ее последней и отключается
+var errPushTooLarge = errors.New("subscription message exceeds max message size")
+
+// messageLimit - ограничение max_message_size для запросов и ответов и счетчики отклоненных сообщений
+type messageLimit struct {
+	maxBytes  int64
+	requests  atomic.Uint64
+	responses atomic.Uint64
+}
+
+func newMessageLimit(maxMessageSize string) (*messageLimit, error) {
+	maxBytes, err := config.ParseSizeInBytes(maxMessageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &messageLimit{maxBytes: maxBytes}, nil
+}
+
+func (l *messageLimit) rejectRequest() Response {
+	l.requests.Add(1)
+	return ErrorResponse(StatusMessageTooLarge, fmt.Errorf("request exceeds max message size of %d bytes", l.maxBytes))
+}
+
+// encode кодирует ответ. Ответ со значением больше лимита заменяется ошибкой, чтобы клиент не получил
+// его обрезанным, поэтому вместе с закодированным ответом возвращается фактически отправляемый.
+// Ответы без значения формирует сам сервер, они не ограничиваются
+func (l *messageLimit) encode(encoding Encoding, response Response) ([]byte, Response, error) {
+	encoded, err := EncodeResponse(encoding, response)
+	if err != nil || (response.Value == nil && response.Values == nil) || int64(len(encoded)) <= l.maxBytes {
+		return encoded, response, err
+	}
+
+	l.responses.Add(1)
+	response = ErrorResponse(StatusMessageTooLarge, fmt.Errorf("response exceeds max message size of %d bytes", l.maxBytes))
+	encoded, err = EncodeResponse(encoding, response)
+	return encoded, response, err
+}
+
+func (l *messageLimit) stats() map[string]uint64 {
+	return map[string]uint64{
+		DirectionRequest:  l.requests.Load(),
+		DirectionResponse: l.responses.Load(),
+	}
+}
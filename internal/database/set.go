@@ -0,0 +1,94 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// setWrite выполняет SADD и SREM. Запрос пишется в WAL под блокировкой ключа через walCommit
+func (d *keyspace) setWrite(query compute.Query, timings *Timings) (network.Response, error) {
+	count, err := d.updateSet(query, d.walCommit(timings))
+
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+
+	event := KeyEventSAdd
+	if query.CommandId == compute.SRemCommandId {
+		event = KeyEventSRem
+	}
+	d.notify(true, query.Args[0], event)
+
+	return network.ValueResponse(strconv.Itoa(count)), nil
+}
+
+// updateSet применяет SADD или SREM и возвращает число добавленных или удаленных элементов.
+// commit == nil при восстановлении из WAL. Пустое множество удаляется вместе с ключом
+func (d *keyspace) updateSet(query compute.Query, commit func(compute.Query) error) (int, error) {
+	key, members := query.Args[0], query.Args[1:]
+
+	count := 0
+	err := d.engine.Update(key, func(value engine.Value) (engine.Value, error) {
+		if err := checkType(value, engine.TypeSet); err != nil {
+			return value, err
+		}
+		if value.Type == engine.TypeNone {
+			value = engine.Value{Type: engine.TypeSet, Set: make(map[string]struct{})}
+		}
+
+		if commit != nil {
+			if err := commit(query); err != nil {
+				return value, err
+			}
+		}
+
+		for _, member := range members {
+			_, exists := value.Set[member]
+			if query.CommandId == compute.SAddCommandId && !exists {
+				value.Set[member] = struct{}{}
+				count++
+			} else if query.CommandId == compute.SRemCommandId && exists {
+				delete(value.Set, member)
+				count++
+			}
+		}
+
+		if len(value.Set) == 0 {
+			return engine.Value{}, nil
+		}
+		return value, nil
+	})
+
+	return count, err
+}
+
+// setRead выполняет SMEMBERS, SISMEMBER и SCARD
+func (d *keyspace) setRead(query compute.Query) (network.Response, error) {
+	var response network.Response
+	err := d.engine.View(query.Args[0], func(value engine.Value) error {
+		if err := checkType(value, engine.TypeSet); err != nil {
+			return err
+		}
+
+		switch query.CommandId {
+		case compute.SMembersCommandId:
+			// Элементы сортируются, чтобы ответ не зависел от порядка обхода map
+			response = network.ValuesResponse(toValues(slices.Sorted(maps.Keys(value.Set))))
+		case compute.SIsMemberCommandId:
+			_, exists := value.Set[query.Args[1]]
+			response = network.ValueResponse(boolValue(exists))
+		case compute.SCardCommandId:
+			response = network.ValueResponse(strconv.Itoa(len(value.Set)))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+	return response, nil
+}
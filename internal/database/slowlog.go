@@ -0,0 +1,112 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/network"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Timings - длительность фаз выполнения запроса. Фазы, которых у запроса не было, остаются нулевыми:
+// например, WAL для чтения или все, кроме разбора, для нераспознанной команды
+type Timings struct {
+	Parse time.Duration
+	Wal   time.Duration
+	Apply time.Duration
+}
+
+type SlowLogEntry struct {
+	ID       uint64
+	Time     time.Time
+	Duration time.Duration
+	Timings  Timings
+	Query    string
+}
+
+// SlowLog хранит последние запросы, выполнявшиеся дольше порога, в кольцевом буфере
+type SlowLog struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	entries   []SlowLogEntry
+	next      int
+	full      bool
+	lastID    uint64
+	// logger дублирует медленные запросы в лог, nil - не дублировать
+	logger *zap.Logger
+}
+
+func NewSlowLog(threshold time.Duration, maxLen int, logger *zap.Logger) *SlowLog {
+	if maxLen <= 0 {
+		maxLen = 1
+	}
+
+	return &SlowLog{
+		threshold: threshold,
+		entries:   make([]SlowLogEntry, maxLen),
+		logger:    logger,
+	}
+}
+
+// Observe сохраняет запрос, если он выполнялся не меньше порога
+func (l *SlowLog) Observe(query string, duration time.Duration, timings Timings) {
+	if duration < l.threshold {
+		return
+	}
+
+	query = network.RedactQuery(query)
+
+	l.mu.Lock()
+	l.lastID++
+	entry := SlowLogEntry{
+		ID:       l.lastID,
+		Time:     time.Now(),
+		Duration: duration,
+		Timings:  timings,
+		Query:    query,
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+	l.mu.Unlock()
+
+	if l.logger != nil {
+		l.logger.Warn("slow query",
+			zap.Uint64("id", entry.ID),
+			zap.String("query", query),
+			zap.Duration("duration", duration),
+			zap.Duration("parse", timings.Parse),
+			zap.Duration("wal", timings.Wal),
+			zap.Duration("apply", timings.Apply),
+		)
+	}
+}
+
+// Entries возвращает сохраненные запросы, начиная с последнего
+func (l *SlowLog) Entries() []SlowLogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+
+	entries := make([]SlowLogEntry, 0, count)
+	for i := 1; i <= count; i++ {
+		entries = append(entries, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+
+	return entries
+}
+
+func (l *SlowLog) Reset() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	clear(l.entries)
+	l.next = 0
+	l.full = false
+}
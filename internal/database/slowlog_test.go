@@ -0,0 +1,38 @@
+//go:build unit
+
+package database_test
+
+import (
+	"concurrency_hw/internal/database"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSlowLog(t *testing.T) {
+	slowLog := database.NewSlowLog(10*time.Millisecond, 2, nil)
+
+	slowLog.Observe("GET fast", time.Millisecond, database.Timings{})
+	assert.Empty(t, slowLog.Entries())
+
+	slowLog.Observe("SET a 1", 20*time.Millisecond, database.Timings{Wal: 15 * time.Millisecond})
+	slowLog.Observe("AUTH admin secret", 30*time.Millisecond, database.Timings{})
+	slowLog.Observe("DEL a", 40*time.Millisecond, database.Timings{})
+
+	entries := slowLog.Entries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, uint64(3), entries[0].ID)
+		assert.Equal(t, "DEL a", entries[0].Query)
+		assert.Equal(t, "AUTH admin ***", entries[1].Query)
+		assert.Equal(t, 30*time.Millisecond, entries[1].Duration)
+	}
+
+	slowLog.Reset()
+	assert.Empty(t, slowLog.Entries())
+
+	slowLog.Observe("GET a", 10*time.Millisecond, database.Timings{})
+	entries = slowLog.Entries()
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, uint64(4), entries[0].ID)
+	}
+}
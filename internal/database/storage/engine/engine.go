@@ -1,7 +1,75 @@
 package engine
 
+import "errors"
+
+var (
+	ErrNotInteger    = errors.New("value is not an integer")
+	ErrOverflow      = errors.New("increment or decrement would overflow")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrUnsupported   = errors.New("operation is not supported by the engine")
+)
+
+// ScanDone - курсор, с которого начинается обход и которым он заканчивается
+const ScanDone = "0"
+
 type Engine interface {
-	Set(key, value string)
-	Get(key string) string
-	Del(key string)
+	// Set записывает строку, заменяя значение любого типа. commit записей строк, как и у IncrBy, вызывается
+	// под блокировкой ключей до изменения, его ошибка отменяет изменение
+	Set(key, value string, commit func() error) error
+	// Get возвращает ErrWrongType, если значение ключа не строка
+	Get(key string) (string, bool, error)
+	// Del удаляет значение любого типа
+	Del(key string, commit func() error) error
+	// MSet, MGet и MDel работают с несколькими ключами под одной блокировкой: другие запросы
+	// видят либо все изменения, либо ни одного
+	MSet(keys, values []string, commit func() error) error
+	// MGet возвращает nil для отсутствующих ключей и ключей, значение которых не строка
+	MGet(keys []string) []*string
+	// MDel возвращает число удаленных ключей
+	MDel(keys []string, commit func() error) (int, error)
+	// IncrBy атомарно прибавляет delta к значению ключа как к int64, отсутствующий ключ считается нулем.
+	// commit получает новое значение под блокировкой движка до его записи, ошибка commit отменяет изменение.
+	// Так запись в WAL идет в том же порядке, в котором изменения применяются к движку
+	IncrBy(key string, delta int64, commit func(value string) error) (int64, error)
+	// View вызывает fn со значением ключа под блокировкой чтения. fn не должна сохранять ссылки на Hash после возврата
+	View(key string, fn func(value Value) error) error
+	// Update вызывает fn со значением ключа (TypeNone, если ключа нет) под блокировкой записи и сохраняет
+	// возвращенное значение, значение с TypeNone удаляет ключ. Ошибка fn оставляет ключ без изменений,
+	// поэтому fn должна менять Hash на месте только после всех проверок
+	Update(key string, fn func(value Value) (Value, error)) error
+	// Exists возвращает число существующих ключей любого типа. Повторенный ключ считается столько раз, сколько указан
+	Exists(keys []string) int
+	// Scan возвращает порцию ключей, подходящих под match (nil - все ключи), начиная с cursor, и курсор
+	// следующей порции. Обход начинается и заканчивается курсором ScanDone. Ключ, существовавший все время обхода,
+	// возвращается хотя бы один раз. count - желаемый, а не точный размер порции.
+	// Реализация не должна держать блокировку всего движка на время обхода
+	Scan(cursor string, count int, match func(key string) bool) ([]string, string, error)
+	// Len возвращает число ключей
+	Len() int
+	// Flush удаляет все ключи. Ожидающие BLPOP остаются в очередях
+	Flush()
+	// Waiters возвращает очереди ожидающих элементов списков (BLPOP)
+	Waiters() *Waiters
+}
+
+// Ordered - движок, хранящий ключи в лексикографическом порядке. Движки без порядка его не реализуют,
+// и упорядоченные команды на них возвращают ErrUnsupported
+type Ordered interface {
+	// Range возвращает ключи start <= key < end, подходящие под match (nil - все), по порядку.
+	// Пустой end - без верхней границы, limit <= 0 - без ограничения
+	Range(start, end string, limit int, match func(key string) bool) []string
+	Prefix(prefix string, limit int, match func(key string) bool) []string
+}
+
+// PrefixEnd возвращает наименьшую строку, большую всех строк с префиксом prefix, или пустую строку,
+// если такой нет (префикс пустой или из одних байтов 0xff)
+func PrefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	return ""
 }
@@ -1,35 +1,297 @@
 package mem
 
-import "sync"
+import (
+	"cmp"
+	"concurrency_hw/internal/database/storage/engine"
+	"hash/maphash"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+)
 
+// shardCount - число шардов. Оно не меняется, поэтому ключ всю жизнь живет в одном шарде,
+// и курсор SCAN (номер шарда) остается стабильным при любых изменениях данных
+const shardCount = 256
+
+// InMemoryEngine хранит ключи в шардах с отдельными блокировками. Команды с несколькими ключами
+// блокируют свои шарды в порядке номеров, поэтому остаются атомарными и не приводят к взаимоблокировкам
 type InMemoryEngine struct {
-	storage map[string]string
+	shards  [shardCount]shard
+	seed    maphash.Seed
+	waiters *engine.Waiters
+}
+
+type shard struct {
 	mu      sync.RWMutex
+	storage map[string]engine.Value
 }
 
 func NewInMemoryEngine(initialSize int) *InMemoryEngine {
-	return &InMemoryEngine{
-		storage: make(map[string]string, initialSize),
+	e := &InMemoryEngine{seed: maphash.MakeSeed(), waiters: engine.NewWaiters()}
+	for i := range e.shards {
+		e.shards[i].storage = make(map[string]engine.Value, initialSize/shardCount)
 	}
+	return e
 }
 
-func (e *InMemoryEngine) Set(key, value string) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+func (e *InMemoryEngine) shardIndex(key string) int {
+	return int(maphash.String(e.seed, key) % shardCount)
+}
+
+func (e *InMemoryEngine) shard(key string) *shard {
+	return &e.shards[e.shardIndex(key)]
+}
 
-	e.storage[key] = value
+// lock блокирует шарды ключей в порядке номеров и возвращает функцию разблокировки
+func (e *InMemoryEngine) lock(keys []string, write bool) func() {
+	indexes := make([]int, 0, len(keys))
+	for _, key := range keys {
+		indexes = append(indexes, e.shardIndex(key))
+	}
+	slices.Sort(indexes)
+	indexes = slices.Compact(indexes)
+
+	for _, i := range indexes {
+		if write {
+			e.shards[i].mu.Lock()
+		} else {
+			e.shards[i].mu.RLock()
+		}
+	}
+
+	return func() {
+		for _, i := range indexes {
+			if write {
+				e.shards[i].mu.Unlock()
+			} else {
+				e.shards[i].mu.RUnlock()
+			}
+		}
+	}
+}
+
+func (e *InMemoryEngine) Set(key, value string, commit func() error) error {
+	s := e.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	s.storage[key] = engine.StringValue(value)
+	return nil
+}
+
+func (e *InMemoryEngine) Get(key string) (string, bool, error) {
+	s := e.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, exists := s.storage[key]
+	if !exists {
+		return "", false, nil
+	}
+	if value.Type != engine.TypeString {
+		return "", false, engine.ErrWrongType
+	}
+	return value.Str, true, nil
 }
 
-func (e *InMemoryEngine) Get(key string) string {
-	e.mu.RLock()
-	defer e.mu.RUnlock()
+func (e *InMemoryEngine) Del(key string, commit func() error) error {
+	s := e.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	delete(s.storage, key)
+	return nil
+}
+
+func (e *InMemoryEngine) MSet(keys, values []string, commit func() error) error {
+	defer e.lock(keys, true)()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	for i, key := range keys {
+		e.shard(key).storage[key] = engine.StringValue(values[i])
+	}
+	return nil
+}
+
+func (e *InMemoryEngine) MGet(keys []string) []*string {
+	defer e.lock(keys, false)()
+
+	values := make([]*string, len(keys))
+	for i, key := range keys {
+		if value, exists := e.shard(key).storage[key]; exists && value.Type == engine.TypeString {
+			values[i] = &value.Str
+		}
+	}
+
+	return values
+}
+
+func (e *InMemoryEngine) MDel(keys []string, commit func() error) (int, error) {
+	defer e.lock(keys, true)()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return 0, err
+		}
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		s := e.shard(key)
+		if _, exists := s.storage[key]; exists {
+			delete(s.storage, key)
+			deleted++
+		}
+	}
 
-	return e.storage[key]
+	return deleted, nil
 }
 
-func (e *InMemoryEngine) Del(key string) {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+func (e *InMemoryEngine) IncrBy(key string, delta int64, commit func(value string) error) (int64, error) {
+	s := e.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	delete(e.storage, key)
+	var current int64
+	if value, exists := s.storage[key]; exists {
+		if value.Type != engine.TypeString {
+			return 0, engine.ErrWrongType
+		}
+		parsed, err := strconv.ParseInt(value.Str, 10, 64)
+		if err != nil {
+			return 0, engine.ErrNotInteger
+		}
+		current = parsed
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, engine.ErrOverflow
+	}
+
+	result := current + delta
+	value := strconv.FormatInt(result, 10)
+
+	if commit != nil {
+		if err := commit(value); err != nil {
+			return 0, err
+		}
+	}
+
+	s.storage[key] = engine.StringValue(value)
+	return result, nil
+}
+
+func (e *InMemoryEngine) View(key string, fn func(value engine.Value) error) error {
+	s := e.shard(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return fn(s.storage[key])
+}
+
+func (e *InMemoryEngine) Update(key string, fn func(value engine.Value) (engine.Value, error)) error {
+	s := e.shard(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, err := fn(s.storage[key])
+	if err != nil {
+		return err
+	}
+
+	if value.Type == engine.TypeNone {
+		delete(s.storage, key)
+	} else {
+		s.storage[key] = value
+	}
+	return nil
+}
+
+func (e *InMemoryEngine) Exists(keys []string) int {
+	defer e.lock(keys, false)()
+
+	count := 0
+	for _, key := range keys {
+		if _, exists := e.shard(key).storage[key]; exists {
+			count++
+		}
+	}
+	return count
+}
+
+// Scan - курсор это номер следующего шарда. За вызов шарды читаются целиком, по одному под своей блокировкой,
+// пока не наберется count ключей, поэтому ключей может вернуться больше count
+func (e *InMemoryEngine) Scan(cursor string, count int, match func(key string) bool) ([]string, string, error) {
+	start, err := strconv.Atoi(cursor)
+	if err != nil || start < 0 || start >= shardCount {
+		return nil, "", engine.ErrInvalidCursor
+	}
+
+	var keys []string
+	for i := start; i < shardCount; i++ {
+		keys = e.shards[i].appendKeys(keys, match)
+
+		if len(keys) >= count && i+1 < shardCount {
+			return keys, strconv.Itoa(i + 1), nil
+		}
+	}
+
+	return keys, engine.ScanDone, nil
+}
+
+func (s *shard) appendKeys(keys []string, match func(key string) bool) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start := len(keys)
+	for key := range s.storage {
+		if match == nil || match(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	// Внутри шарда порядок ключей не важен для курсора, но сортировка делает ответы воспроизводимыми
+	slices.SortFunc(keys[start:], cmp.Compare[string])
+	return keys
+}
+
+func (e *InMemoryEngine) Len() int {
+	total := 0
+	for i := range e.shards {
+		e.shards[i].mu.RLock()
+		total += len(e.shards[i].storage)
+		e.shards[i].mu.RUnlock()
+	}
+	return total
+}
+
+func (e *InMemoryEngine) Waiters() *engine.Waiters {
+	return e.waiters
+}
+
+func (e *InMemoryEngine) Flush() {
+	for i := range e.shards {
+		s := &e.shards[i]
+		s.mu.Lock()
+		s.storage = make(map[string]engine.Value)
+		s.mu.Unlock()
+	}
 }
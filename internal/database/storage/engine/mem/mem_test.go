@@ -3,7 +3,9 @@
 package mem_test
 
 import (
+	storage "concurrency_hw/internal/database/storage/engine"
 	"concurrency_hw/internal/database/storage/engine/mem"
+	"errors"
 	"fmt"
 	"testing"
 )
@@ -16,20 +18,31 @@ func TestInMemoryEngine(t *testing.T) {
 		key := "testKey"
 		value := "testValue"
 
-		engine.Set(key, value)
-		got := engine.Get(key)
+		engine.Set(key, value, nil)
+		got, exists, _ := engine.Get(key)
 
-		if got != value {
-			t.Errorf("Get() = %v, want %v", got, value)
+		if got != value || !exists {
+			t.Errorf("Get() = %v, %v, want %v, true", got, exists, value)
 		}
 	})
 
 	t.Run("Get non-existent key", func(t *testing.T) {
 		key := "nonExistentKey"
-		got := engine.Get(key)
+		got, exists, _ := engine.Get(key)
 
-		if got != "" {
-			t.Errorf("Get() for non-existent key = %v, want empty string", got)
+		if got != "" || exists {
+			t.Errorf("Get() for non-existent key = %v, %v, want empty string, false", got, exists)
+		}
+	})
+
+	t.Run("Get empty value", func(t *testing.T) {
+		key := "emptyKey"
+
+		engine.Set(key, "", nil)
+		got, exists, _ := engine.Get(key)
+
+		if got != "" || !exists {
+			t.Errorf("Get() for empty value = %v, %v, want empty string, true", got, exists)
 		}
 	})
 
@@ -37,12 +50,12 @@ func TestInMemoryEngine(t *testing.T) {
 		key := "keyToDelete"
 		value := "valueToDelete"
 
-		engine.Set(key, value)
-		engine.Del(key)
-		got := engine.Get(key)
+		engine.Set(key, value, nil)
+		engine.Del(key, nil)
+		got, exists, _ := engine.Get(key)
 
-		if got != "" {
-			t.Errorf("Get() after Del() = %v, want empty string", got)
+		if got != "" || exists {
+			t.Errorf("Get() after Del() = %v, %v, want empty string, false", got, exists)
 		}
 	})
 
@@ -51,14 +64,213 @@ func TestInMemoryEngine(t *testing.T) {
 		value1 := "firstValue"
 		value2 := "secondValue"
 
-		engine.Set(key, value1)
-		engine.Set(key, value2)
-		got := engine.Get(key)
+		engine.Set(key, value1, nil)
+		engine.Set(key, value2, nil)
+		got, _, _ := engine.Get(key)
 
 		if got != value2 {
 			t.Errorf("Get() after overwrite = %v, want %v", got, value2)
 		}
 	})
+
+	t.Run("Multiple keys", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+		engine.MSet([]string{"a", "b"}, []string{"1", "2"}, nil)
+
+		values := engine.MGet([]string{"a", "missing", "b"})
+		if len(values) != 3 || values[0] == nil || *values[0] != "1" || values[1] != nil || values[2] == nil || *values[2] != "2" {
+			t.Errorf("MGet() = %v, want [1 <nil> 2]", values)
+		}
+
+		if got, _ := engine.MDel([]string{"a", "a", "missing"}, nil); got != 1 {
+			t.Errorf("MDel() = %v, want 1", got)
+		}
+		if got := engine.Len(); got != 1 {
+			t.Errorf("Len() after MDel = %v, want 1", got)
+		}
+	})
+
+	t.Run("Failed commit", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+		engine.Set("a", "1", nil)
+
+		failed := func() error {
+			return errors.New("wal is unavailable")
+		}
+		if err := engine.Set("a", "2", failed); err == nil {
+			t.Error("Set() with failed commit: expected error")
+		}
+		if err := engine.MSet([]string{"a", "b"}, []string{"3", "4"}, failed); err == nil {
+			t.Error("MSet() with failed commit: expected error")
+		}
+		if _, err := engine.MDel([]string{"a"}, failed); err == nil {
+			t.Error("MDel() with failed commit: expected error")
+		}
+
+		if got, _, _ := engine.Get("a"); got != "1" || engine.Len() != 1 {
+			t.Errorf("Get() after failed commits = %v, Len() = %v, want 1, 1", got, engine.Len())
+		}
+	})
+
+	t.Run("IncrBy", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+
+		var committed []string
+		commit := func(value string) error {
+			committed = append(committed, value)
+			return nil
+		}
+
+		if got, err := engine.IncrBy("counter", 5, commit); err != nil || got != 5 {
+			t.Errorf("IncrBy() = %v, %v, want 5", got, err)
+		}
+		if got, err := engine.IncrBy("counter", -7, commit); err != nil || got != -2 {
+			t.Errorf("IncrBy() = %v, %v, want -2", got, err)
+		}
+
+		failed := errors.New("wal is broken")
+		if _, err := engine.IncrBy("counter", 1, func(string) error { return failed }); !errors.Is(err, failed) {
+			t.Errorf("IncrBy() error = %v, want %v", err, failed)
+		}
+		if got, _, _ := engine.Get("counter"); got != "-2" {
+			t.Errorf("Get() after failed commit = %v, want -2", got)
+		}
+
+		engine.Set("max", "9223372036854775807", nil)
+		if _, err := engine.IncrBy("max", 1, commit); !errors.Is(err, storage.ErrOverflow) {
+			t.Errorf("IncrBy() error = %v, want overflow", err)
+		}
+
+		engine.Set("text", "abc", nil)
+		if _, err := engine.IncrBy("text", 1, commit); !errors.Is(err, storage.ErrNotInteger) {
+			t.Errorf("IncrBy() error = %v, want not integer", err)
+		}
+
+		if len(committed) != 2 || committed[0] != "5" || committed[1] != "-2" {
+			t.Errorf("committed = %v, want [5 -2]", committed)
+		}
+	})
+
+	t.Run("Typed values", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+
+		err := engine.Update("hash", func(value storage.Value) (storage.Value, error) {
+			if value.Type != storage.TypeNone {
+				t.Errorf("Update() value type = %v, want none", value.Type)
+			}
+			return storage.Value{Type: storage.TypeHash, Hash: map[string]string{"f": "v"}}, nil
+		})
+		if err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+
+		if _, _, err := engine.Get("hash"); !errors.Is(err, storage.ErrWrongType) {
+			t.Errorf("Get() error = %v, want wrong type", err)
+		}
+		if _, err := engine.IncrBy("hash", 1, nil); !errors.Is(err, storage.ErrWrongType) {
+			t.Errorf("IncrBy() error = %v, want wrong type", err)
+		}
+		if values := engine.MGet([]string{"hash"}); values[0] != nil {
+			t.Errorf("MGet() = %v, want nil for hash", *values[0])
+		}
+		if got := engine.Exists([]string{"hash", "missing", "hash"}); got != 2 {
+			t.Errorf("Exists() = %v, want 2", got)
+		}
+
+		_ = engine.View("hash", func(value storage.Value) error {
+			if value.Type != storage.TypeHash || value.Hash["f"] != "v" {
+				t.Errorf("View() value = %+v, want hash with f=v", value)
+			}
+			return nil
+		})
+
+		failed := errors.New("rejected")
+		if err := engine.Update("hash", func(storage.Value) (storage.Value, error) {
+			return storage.Value{}, failed
+		}); !errors.Is(err, failed) {
+			t.Errorf("Update() error = %v, want %v", err, failed)
+		}
+		if engine.Len() != 1 {
+			t.Errorf("Len() after failed Update = %v, want 1", engine.Len())
+		}
+
+		_ = engine.Update("hash", func(storage.Value) (storage.Value, error) {
+			return storage.Value{}, nil
+		})
+		if engine.Len() != 0 {
+			t.Errorf("Len() after Update to none = %v, want 0", engine.Len())
+		}
+	})
+
+	t.Run("Scan", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+		for i := range 1000 {
+			engine.Set(fmt.Sprintf("key%d", i), "value", nil)
+		}
+		engine.Set("other", "value", nil)
+
+		seen := make(map[string]int)
+		cursor, calls := storage.ScanDone, 0
+		for {
+			keys, next, err := engine.Scan(cursor, 50, func(key string) bool { return key != "other" })
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			for _, key := range keys {
+				seen[key]++
+			}
+
+			// Изменения во время обхода не мешают вернуть ключи, существовавшие все время
+			engine.Set(fmt.Sprintf("new%d", calls), "value", nil)
+			calls++
+
+			if next == storage.ScanDone {
+				break
+			}
+			cursor = next
+		}
+
+		if calls < 2 {
+			t.Errorf("Scan() finished in %d calls, want several", calls)
+		}
+		for i := range 1000 {
+			if key := fmt.Sprintf("key%d", i); seen[key] != 1 {
+				t.Errorf("key %s returned %d times, want 1", key, seen[key])
+			}
+		}
+		if seen["other"] != 0 {
+			t.Errorf("Scan() returned key rejected by match")
+		}
+
+		if _, _, err := engine.Scan("abc", 10, nil); !errors.Is(err, storage.ErrInvalidCursor) {
+			t.Errorf("Scan() error = %v, want invalid cursor", err)
+		}
+	})
+
+	t.Run("Len", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+		engine.Set("a", "1", nil)
+		engine.Set("b", "2", nil)
+		engine.Set("a", "3", nil)
+		engine.Del("b", nil)
+
+		if got := engine.Len(); got != 1 {
+			t.Errorf("Len() = %v, want 1", got)
+		}
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		engine := mem.NewInMemoryEngine(initialSize)
+		engine.MSet([]string{"a", "b", "c"}, []string{"1", "2", "3"}, nil)
+		engine.Flush()
+
+		if got := engine.Len(); got != 0 {
+			t.Errorf("Len() = %v, want 0", got)
+		}
+		if _, ok, _ := engine.Get("a"); ok {
+			t.Errorf("Get() after Flush found a key")
+		}
+	})
 }
 
 func TestConcurrency(t *testing.T) {
@@ -77,21 +289,21 @@ func TestConcurrency(t *testing.T) {
 					value := fmt.Sprintf("value_%d_%d", id, j)
 
 					// Set value
-					engine.Set(key, value)
+					engine.Set(key, value, nil)
 
 					// Get and verify value
-					got := engine.Get(key)
+					got, _, _ := engine.Get(key)
 					if got != value {
 						errors <- fmt.Errorf("goroutine %d: expected value %s, got %s", id, value, got)
 						return
 					}
 
 					// Delete value
-					engine.Del(key)
+					engine.Del(key, nil)
 
 					// Verify deletion
-					got = engine.Get(key)
-					if got != "" {
+					got, exists, _ := engine.Get(key)
+					if got != "" || exists {
 						errors <- fmt.Errorf("goroutine %d: expected empty value after deletion, got %s", id, got)
 						return
 					}
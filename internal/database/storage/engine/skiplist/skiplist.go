@@ -0,0 +1,370 @@
+package skiplist
+
+import (
+	"concurrency_hw/internal/database/storage/engine"
+	"encoding/hex"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	maxLevel = 32
+	// levelProbability - вероятность, что узел поднимется на следующий уровень
+	levelProbability = 0.25
+
+	// cursorPrefix отличает курсор SCAN от engine.ScanDone: курсор - последний просмотренный ключ в hex
+	cursorPrefix = "k"
+)
+
+// SkipListEngine хранит ключи в списке с пропусками в лексикографическом порядке.
+// Все операции идут под одной блокировкой, поиск ключа - O(log n)
+type SkipListEngine struct {
+	mu      sync.RWMutex
+	head    *node
+	level   int
+	length  int
+	waiters *engine.Waiters
+}
+
+type node struct {
+	key   string
+	value engine.Value
+	next  []*node
+}
+
+func NewSkipListEngine() *SkipListEngine {
+	return &SkipListEngine{
+		head:    &node{next: make([]*node, maxLevel)},
+		level:   1,
+		waiters: engine.NewWaiters(),
+	}
+}
+
+func randomLevel() int {
+	level := 1
+	for level < maxLevel && rand.Float64() < levelProbability {
+		level++
+	}
+	return level
+}
+
+// seek возвращает первый узел с ключом >= key. Если update не nil, в него пишутся последние узлы
+// каждого уровня перед найденной позицией
+func (e *SkipListEngine) seek(key string, update []*node) *node {
+	current := e.head
+	for i := e.level - 1; i >= 0; i-- {
+		for current.next[i] != nil && current.next[i].key < key {
+			current = current.next[i]
+		}
+		if update != nil {
+			update[i] = current
+		}
+	}
+	return current.next[0]
+}
+
+func (e *SkipListEngine) find(key string) *node {
+	if n := e.seek(key, nil); n != nil && n.key == key {
+		return n
+	}
+	return nil
+}
+
+func (e *SkipListEngine) set(key string, value engine.Value) {
+	update := make([]*node, maxLevel)
+	if n := e.seek(key, update); n != nil && n.key == key {
+		n.value = value
+		return
+	}
+
+	level := randomLevel()
+	for i := e.level; i < level; i++ {
+		update[i] = e.head
+	}
+	e.level = max(e.level, level)
+
+	n := &node{key: key, value: value, next: make([]*node, level)}
+	for i := range level {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+	e.length++
+}
+
+func (e *SkipListEngine) del(key string) bool {
+	update := make([]*node, maxLevel)
+	n := e.seek(key, update)
+	if n == nil || n.key != key {
+		return false
+	}
+
+	for i := range n.next {
+		update[i].next[i] = n.next[i]
+	}
+	for e.level > 1 && e.head.next[e.level-1] == nil {
+		e.level--
+	}
+	e.length--
+	return true
+}
+
+func (e *SkipListEngine) Set(key, value string, commit func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	e.set(key, engine.StringValue(value))
+	return nil
+}
+
+func (e *SkipListEngine) Get(key string) (string, bool, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	n := e.find(key)
+	if n == nil {
+		return "", false, nil
+	}
+	if n.value.Type != engine.TypeString {
+		return "", false, engine.ErrWrongType
+	}
+	return n.value.Str, true, nil
+}
+
+func (e *SkipListEngine) Del(key string, commit func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	e.del(key)
+	return nil
+}
+
+func (e *SkipListEngine) MSet(keys, values []string, commit func() error) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return err
+		}
+	}
+
+	for i, key := range keys {
+		e.set(key, engine.StringValue(values[i]))
+	}
+	return nil
+}
+
+func (e *SkipListEngine) MGet(keys []string) []*string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	values := make([]*string, len(keys))
+	for i, key := range keys {
+		if n := e.find(key); n != nil && n.value.Type == engine.TypeString {
+			value := n.value.Str
+			values[i] = &value
+		}
+	}
+	return values
+}
+
+func (e *SkipListEngine) MDel(keys []string, commit func() error) (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if commit != nil {
+		if err := commit(); err != nil {
+			return 0, err
+		}
+	}
+
+	deleted := 0
+	for _, key := range keys {
+		if e.del(key) {
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (e *SkipListEngine) IncrBy(key string, delta int64, commit func(value string) error) (int64, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var current int64
+	if n := e.find(key); n != nil {
+		if n.value.Type != engine.TypeString {
+			return 0, engine.ErrWrongType
+		}
+		parsed, err := strconv.ParseInt(n.value.Str, 10, 64)
+		if err != nil {
+			return 0, engine.ErrNotInteger
+		}
+		current = parsed
+	}
+
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, engine.ErrOverflow
+	}
+
+	result := current + delta
+	value := strconv.FormatInt(result, 10)
+
+	if commit != nil {
+		if err := commit(value); err != nil {
+			return 0, err
+		}
+	}
+
+	e.set(key, engine.StringValue(value))
+	return result, nil
+}
+
+func (e *SkipListEngine) View(key string, fn func(value engine.Value) error) error {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var value engine.Value
+	if n := e.find(key); n != nil {
+		value = n.value
+	}
+	return fn(value)
+}
+
+func (e *SkipListEngine) Update(key string, fn func(value engine.Value) (engine.Value, error)) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var current engine.Value
+	if n := e.find(key); n != nil {
+		current = n.value
+	}
+
+	value, err := fn(current)
+	if err != nil {
+		return err
+	}
+
+	if value.Type == engine.TypeNone {
+		e.del(key)
+	} else {
+		e.set(key, value)
+	}
+	return nil
+}
+
+func (e *SkipListEngine) Exists(keys []string) int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	count := 0
+	for _, key := range keys {
+		if e.find(key) != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// Scan просматривает не больше count ключей за вызов, начиная после ключа из курсора
+func (e *SkipListEngine) Scan(cursor string, count int, match func(key string) bool) ([]string, string, error) {
+	count = max(count, 1)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var n *node
+	if cursor == engine.ScanDone {
+		n = e.head.next[0]
+	} else {
+		last, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		n = e.seek(last, nil)
+		if n != nil && n.key == last {
+			n = n.next[0]
+		}
+	}
+
+	var keys []string
+	var last *node
+	for examined := 0; n != nil && examined < count; examined++ {
+		if match == nil || match(n.key) {
+			keys = append(keys, n.key)
+		}
+		last, n = n, n.next[0]
+	}
+
+	if n == nil {
+		return keys, engine.ScanDone, nil
+	}
+	return keys, cursorPrefix + hex.EncodeToString([]byte(last.key)), nil
+}
+
+func decodeCursor(cursor string) (string, error) {
+	if !strings.HasPrefix(cursor, cursorPrefix) {
+		return "", engine.ErrInvalidCursor
+	}
+
+	key, err := hex.DecodeString(cursor[len(cursorPrefix):])
+	if err != nil {
+		return "", engine.ErrInvalidCursor
+	}
+	return string(key), nil
+}
+
+func (e *SkipListEngine) Range(start, end string, limit int, match func(key string) bool) []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	var keys []string
+	for n := e.seek(start, nil); n != nil && (end == "" || n.key < end); n = n.next[0] {
+		if limit > 0 && len(keys) == limit {
+			break
+		}
+		if match == nil || match(n.key) {
+			keys = append(keys, n.key)
+		}
+	}
+	return keys
+}
+
+func (e *SkipListEngine) Prefix(prefix string, limit int, match func(key string) bool) []string {
+	return e.Range(prefix, engine.PrefixEnd(prefix), limit, match)
+}
+
+func (e *SkipListEngine) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.length
+}
+
+func (e *SkipListEngine) Waiters() *engine.Waiters {
+	return e.waiters
+}
+
+func (e *SkipListEngine) Flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.head = &node{next: make([]*node, maxLevel)}
+	e.level = 1
+	e.length = 0
+}
@@ -0,0 +1,148 @@
+//go:build unit
+
+package skiplist_test
+
+import (
+	storage "concurrency_hw/internal/database/storage/engine"
+	"concurrency_hw/internal/database/storage/engine/skiplist"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipListEngine(t *testing.T) {
+	engine := skiplist.NewSkipListEngine()
+
+	engine.Set("b", "2", nil)
+	engine.Set("a", "1", nil)
+	engine.Set("a", "11", nil)
+
+	value, ok, err := engine.Get("a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "11", value)
+	assert.Equal(t, 2, engine.Len())
+
+	engine.Del("a", nil)
+	_, ok, _ = engine.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, engine.Len())
+
+	engine.MSet([]string{"c", "d"}, []string{"3", "4"}, nil)
+	values := engine.MGet([]string{"c", "missing", "d"})
+	require.Len(t, values, 3)
+	assert.Equal(t, "3", *values[0])
+	assert.Nil(t, values[1])
+	assert.Equal(t, "4", *values[2])
+
+	deleted, err := engine.MDel([]string{"b", "c", "missing"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.Equal(t, 1, engine.Len())
+
+	engine.Flush()
+	assert.Equal(t, 0, engine.Len())
+	engine.Set("a", "1", nil)
+	value, ok, err = engine.Get("a")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "1", value)
+}
+
+func TestSkipListEngine_Ordered(t *testing.T) {
+	engine := skiplist.NewSkipListEngine()
+	for _, key := range []string{"user:3", "order:1", "user:1", "user:10", "user:2", "vip"} {
+		engine.Set(key, "v", nil)
+	}
+
+	assert.Equal(t, []string{"user:1", "user:10", "user:2"}, engine.Range("user:1", "user:3", 0, nil))
+	assert.Equal(t, []string{"user:1", "user:10"}, engine.Range("user:", "", 2, nil))
+	assert.Equal(t, []string{"user:1", "user:10", "user:2", "user:3"}, engine.Prefix("user:", 0, nil))
+	assert.Equal(t, []string{"user:2", "user:3"}, engine.Prefix("user:", 0, func(key string) bool {
+		return key >= "user:2"
+	}))
+	assert.Empty(t, engine.Prefix("none", 0, nil))
+	assert.Equal(t, []string{"order:1", "user:1", "user:10", "user:2", "user:3", "vip"}, engine.Prefix("", 0, nil))
+}
+
+func TestSkipListEngine_Scan(t *testing.T) {
+	engine := skiplist.NewSkipListEngine()
+	for i := range 25 {
+		engine.Set(fmt.Sprintf("key:%02d", i), "v", nil)
+	}
+
+	var keys []string
+	cursor := storage.ScanDone
+	for {
+		batch, next, err := engine.Scan(cursor, 10, nil)
+		require.NoError(t, err)
+		keys = append(keys, batch...)
+		if next == storage.ScanDone {
+			break
+		}
+		cursor = next
+	}
+	assert.Len(t, keys, 25)
+	assert.IsIncreasing(t, keys)
+
+	_, _, err := engine.Scan("oops", 10, nil)
+	assert.True(t, errors.Is(err, storage.ErrInvalidCursor))
+}
+
+func TestSkipListEngine_IncrBy(t *testing.T) {
+	engine := skiplist.NewSkipListEngine()
+
+	value, err := engine.IncrBy("counter", 5, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), value)
+
+	engine.Set("text", "abc", nil)
+	_, err = engine.IncrBy("text", 1, nil)
+	assert.True(t, errors.Is(err, storage.ErrNotInteger))
+
+	engine.Set("max", strconv.FormatInt(1<<63-1, 10), nil)
+	_, err = engine.IncrBy("max", 1, nil)
+	assert.True(t, errors.Is(err, storage.ErrOverflow))
+
+	failed := errors.New("wal failed")
+	_, err = engine.IncrBy("counter", 1, func(string) error { return failed })
+	assert.True(t, errors.Is(err, failed))
+	stored, _, _ := engine.Get("counter")
+	assert.Equal(t, "5", stored)
+}
+
+func TestSkipListEngine_Update(t *testing.T) {
+	engine := skiplist.NewSkipListEngine()
+
+	err := engine.Update("hash", func(value storage.Value) (storage.Value, error) {
+		assert.Equal(t, storage.TypeNone, value.Type)
+		return storage.Value{Type: storage.TypeHash, Hash: map[string]string{"f": "v"}}, nil
+	})
+	require.NoError(t, err)
+
+	_, _, err = engine.Get("hash")
+	assert.ErrorIs(t, err, storage.ErrWrongType)
+	assert.Equal(t, 1, engine.Exists([]string{"hash", "missing"}))
+	assert.Equal(t, []string{"hash"}, engine.Prefix("h", 0, nil))
+
+	require.NoError(t, engine.View("hash", func(value storage.Value) error {
+		assert.Equal(t, "v", value.Hash["f"])
+		return nil
+	}))
+
+	require.NoError(t, engine.Update("hash", func(storage.Value) (storage.Value, error) {
+		return storage.Value{}, nil
+	}))
+	assert.Equal(t, 0, engine.Len())
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, "b", storage.PrefixEnd("a"))
+	assert.Equal(t, "b", storage.PrefixEnd("a\xff"))
+	assert.Equal(t, "", storage.PrefixEnd("\xff"))
+	assert.Equal(t, "", storage.PrefixEnd(""))
+}
@@ -0,0 +1,49 @@
+package engine
+
+import "errors"
+
+var ErrWrongType = errors.New("WRONGTYPE operation against a key holding the wrong kind of value")
+
+// Type - тип значения ключа
+type Type int
+
+const (
+	// TypeNone - ключа нет. Update, вернувший значение с TypeNone, удаляет ключ
+	TypeNone Type = iota
+	TypeString
+	TypeHash
+	TypeList
+	TypeSet
+	TypeZSet
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeHash:
+		return "hash"
+	case TypeList:
+		return "list"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	default:
+		return "none"
+	}
+}
+
+// Value - значение ключа. Заполнено поле, соответствующее Type
+type Value struct {
+	Type Type
+	Str  string
+	Hash map[string]string
+	List []string
+	Set  map[string]struct{}
+	ZSet *ZSet
+}
+
+func StringValue(value string) Value {
+	return Value{Type: TypeString, Str: value}
+}
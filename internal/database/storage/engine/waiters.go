@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"slices"
+	"sync"
+)
+
+// Waiters - очереди клиентов, ожидающих элемент списка (BLPOP). Ожидающий - буферизованный канал в FIFO-очереди
+// своего ключа, поэтому ожидание не держит горутин, а элементы достаются ожидающим в порядке прихода.
+// Add и Serve вызываются внутри Engine.Update ключа, а Remove - внутри Engine.View, поэтому регистрация, выдача
+// и отмена упорядочены с изменениями списка. Пока у ключа есть ожидающие, его список пуст
+type Waiters struct {
+	mu     sync.Mutex
+	queues map[string][]*Waiter
+	count  int
+}
+
+type Waiter struct {
+	key string
+	// C получает выданный ожидающему элемент. Буфер в один элемент, выдача никогда не блокируется
+	C chan string
+}
+
+func NewWaiters() *Waiters {
+	return &Waiters{queues: make(map[string][]*Waiter)}
+}
+
+// Add ставит ожидающего в конец очереди ключа
+func (w *Waiters) Add(key string) *Waiter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	waiter := &Waiter{key: key, C: make(chan string, 1)}
+	w.queues[key] = append(w.queues[key], waiter)
+	w.count++
+	return waiter
+}
+
+// Remove убирает ожидающего из очереди. false - ожидающему уже выдан элемент, и он лежит в C
+func (w *Waiters) Remove(waiter *Waiter) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	queue := w.queues[waiter.key]
+	i := slices.Index(queue, waiter)
+	if i < 0 {
+		return false
+	}
+
+	w.setQueue(waiter.key, slices.Delete(queue, i, i+1))
+	w.count--
+	return true
+}
+
+// Count возвращает число ожидающих ключа
+func (w *Waiters) Count(key string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return len(w.queues[key])
+}
+
+// Serve выдает элементы ожидающим ключа в порядке очереди, по одному на ожидающего, и возвращает невыданные
+func (w *Waiters) Serve(key string, values []string) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	queue := w.queues[key]
+	served := min(len(queue), len(values))
+	for i := range served {
+		queue[i].C <- values[i]
+	}
+
+	w.setQueue(key, queue[served:])
+	w.count -= served
+	return values[served:]
+}
+
+// Len возвращает число ожидающих всех ключей
+func (w *Waiters) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.count
+}
+
+func (w *Waiters) setQueue(key string, queue []*Waiter) {
+	if len(queue) == 0 {
+		delete(w.queues, key)
+	} else {
+		w.queues[key] = queue
+	}
+}
@@ -0,0 +1,36 @@
+//go:build unit
+
+package engine_test
+
+import (
+	"concurrency_hw/internal/database/storage/engine"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaiters(t *testing.T) {
+	waiters := engine.NewWaiters()
+
+	first := waiters.Add("jobs")
+	second := waiters.Add("jobs")
+	third := waiters.Add("jobs")
+	other := waiters.Add("other")
+	assert.Equal(t, 3, waiters.Count("jobs"))
+	assert.Equal(t, 4, waiters.Len())
+
+	// Отмененный ожидающий пропускается, остальные получают элементы в порядке очереди
+	assert.True(t, waiters.Remove(second))
+	assert.Equal(t, []string{"c"}, waiters.Serve("jobs", []string{"a", "b", "c"}))
+	assert.Equal(t, "a", <-first.C)
+	assert.Equal(t, "b", <-third.C)
+
+	// Получивший элемент уже не в очереди
+	assert.False(t, waiters.Remove(first))
+	assert.Equal(t, 0, waiters.Count("jobs"))
+	assert.Empty(t, waiters.Serve("jobs", nil))
+	assert.Equal(t, []string{"x"}, waiters.Serve("jobs", []string{"x"}))
+
+	assert.True(t, waiters.Remove(other))
+	assert.Equal(t, 0, waiters.Len())
+}
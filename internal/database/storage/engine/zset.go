@@ -0,0 +1,191 @@
+package engine
+
+import "math/rand/v2"
+
+const (
+	zsetMaxLevel = 32
+	// zsetLevelProbability - вероятность, что узел поднимется на следующий уровень
+	zsetLevelProbability = 0.25
+)
+
+// ZSet - сортированное множество: map элемент -> оценка для поиска по элементу и список с пропусками,
+// упорядоченный по (оценка, элемент), для выборок по рангу и диапазону оценок. Не потокобезопасно,
+// используется под блокировкой ключа движка
+type ZSet struct {
+	scores map[string]float64
+	head   *zsetNode
+	level  int
+}
+
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+type zsetNode struct {
+	ZMember
+	next []*zsetNode
+}
+
+func NewZSet() *ZSet {
+	return &ZSet{
+		scores: make(map[string]float64),
+		head:   &zsetNode{next: make([]*zsetNode, zsetMaxLevel)},
+		level:  1,
+	}
+}
+
+func (z *ZSet) Len() int {
+	return len(z.scores)
+}
+
+func (z *ZSet) Score(member string) (float64, bool) {
+	score, exists := z.scores[member]
+	return score, exists
+}
+
+// Add добавляет элемент или меняет его оценку. Возвращает true, если элемента не было
+func (z *ZSet) Add(member string, score float64) bool {
+	current, exists := z.scores[member]
+	if exists {
+		if current == score {
+			return false
+		}
+		z.unlink(ZMember{Member: member, Score: current})
+	}
+
+	z.scores[member] = score
+	z.link(ZMember{Member: member, Score: score})
+	return !exists
+}
+
+// Remove удаляет элемент. Возвращает false, если элемента не было
+func (z *ZSet) Remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+
+	delete(z.scores, member)
+	z.unlink(ZMember{Member: member, Score: score})
+	return true
+}
+
+// Range возвращает элементы с рангами от start до stop включительно по возрастанию оценки.
+// Отрицательные ранги считаются от конца
+func (z *ZSet) Range(start, stop int) []ZMember {
+	length := z.Len()
+	if start < 0 {
+		start = max(length+start, 0)
+	}
+	if stop < 0 {
+		stop = length + stop
+	}
+	stop = min(stop, length-1)
+	if start > stop {
+		return nil
+	}
+
+	n := z.head.next[0]
+	for range start {
+		n = n.next[0]
+	}
+
+	members := make([]ZMember, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		members = append(members, n.ZMember)
+		n = n.next[0]
+	}
+	return members
+}
+
+// ScoreBound - граница диапазона оценок, Exclusive - граница не входит в диапазон
+type ScoreBound struct {
+	Score     float64
+	Exclusive bool
+}
+
+// RangeByScore возвращает элементы с оценками между lower и upper по возрастанию, пропуская первые offset.
+// count < 0 - без ограничения числа элементов
+func (z *ZSet) RangeByScore(lower, upper ScoreBound, offset, count int) []ZMember {
+	// Первый узел с оценкой >= lower.Score
+	n := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for n.next[i] != nil && n.next[i].Score < lower.Score {
+			n = n.next[i]
+		}
+	}
+	n = n.next[0]
+
+	var members []ZMember
+	for ; n != nil && count != 0; n = n.next[0] {
+		if lower.Exclusive && n.Score == lower.Score {
+			continue
+		}
+		if n.Score > upper.Score || (upper.Exclusive && n.Score == upper.Score) {
+			break
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+
+		members = append(members, n.ZMember)
+		count--
+	}
+	return members
+}
+
+func less(a, b ZMember) bool {
+	if a.Score != b.Score {
+		return a.Score < b.Score
+	}
+	return a.Member < b.Member
+}
+
+// seek заполняет update последними узлами каждого уровня перед позицией member
+func (z *ZSet) seek(member ZMember, update []*zsetNode) *zsetNode {
+	n := z.head
+	for i := z.level - 1; i >= 0; i-- {
+		for n.next[i] != nil && less(n.next[i].ZMember, member) {
+			n = n.next[i]
+		}
+		update[i] = n
+	}
+	return n.next[0]
+}
+
+func (z *ZSet) link(member ZMember) {
+	update := make([]*zsetNode, zsetMaxLevel)
+	z.seek(member, update)
+
+	level := 1
+	for level < zsetMaxLevel && rand.Float64() < zsetLevelProbability {
+		level++
+	}
+	for i := z.level; i < level; i++ {
+		update[i] = z.head
+	}
+	z.level = max(z.level, level)
+
+	n := &zsetNode{ZMember: member, next: make([]*zsetNode, level)}
+	for i := range level {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+}
+
+func (z *ZSet) unlink(member ZMember) {
+	update := make([]*zsetNode, zsetMaxLevel)
+	n := z.seek(member, update)
+	if n == nil || n.ZMember != member {
+		return
+	}
+
+	for i := range n.next {
+		update[i].next[i] = n.next[i]
+	}
+	for z.level > 1 && z.head.next[z.level-1] == nil {
+		z.level--
+	}
+}
@@ -0,0 +1,79 @@
+//go:build unit
+
+package engine_test
+
+import (
+	"cmp"
+	"concurrency_hw/internal/database/storage/engine"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZSet(t *testing.T) {
+	zset := engine.NewZSet()
+
+	assert.True(t, zset.Add("carol", 30))
+	assert.True(t, zset.Add("alice", 10))
+	assert.True(t, zset.Add("bob", 20))
+	assert.True(t, zset.Add("dave", 20))
+	assert.False(t, zset.Add("alice", 25))
+	assert.False(t, zset.Add("alice", 25))
+	assert.Equal(t, 4, zset.Len())
+
+	score, exists := zset.Score("alice")
+	assert.True(t, exists)
+	assert.Equal(t, 25.0, score)
+
+	// Равные оценки упорядочены по элементу
+	assert.Equal(t, []engine.ZMember{{"bob", 20}, {"dave", 20}, {"alice", 25}, {"carol", 30}}, zset.Range(0, -1))
+	assert.Equal(t, []engine.ZMember{{"alice", 25}, {"carol", 30}}, zset.Range(-2, 10))
+	assert.Empty(t, zset.Range(3, 1))
+
+	all := engine.ScoreBound{Score: math.Inf(1)}
+	assert.Equal(t, []engine.ZMember{{"dave", 20}, {"alice", 25}},
+		zset.RangeByScore(engine.ScoreBound{Score: 20}, engine.ScoreBound{Score: 25}, 1, -1))
+	assert.Equal(t, []engine.ZMember{{"alice", 25}},
+		zset.RangeByScore(engine.ScoreBound{Score: 20, Exclusive: true}, engine.ScoreBound{Score: 30, Exclusive: true}, 0, -1))
+	assert.Equal(t, []engine.ZMember{{"bob", 20}},
+		zset.RangeByScore(engine.ScoreBound{Score: math.Inf(-1)}, all, 0, 1))
+
+	assert.True(t, zset.Remove("bob"))
+	assert.False(t, zset.Remove("bob"))
+	_, exists = zset.Score("bob")
+	assert.False(t, exists)
+	assert.Equal(t, []engine.ZMember{{"dave", 20}, {"alice", 25}, {"carol", 30}}, zset.Range(0, -1))
+}
+
+func TestZSet_Random(t *testing.T) {
+	zset := engine.NewZSet()
+	scores := make(map[string]float64)
+
+	for range 2000 {
+		member := fmt.Sprintf("m%d", rand.N(300))
+		if rand.N(4) == 0 {
+			delete(scores, member)
+			zset.Remove(member)
+			continue
+		}
+
+		score := float64(rand.N(50))
+		scores[member] = score
+		zset.Add(member, score)
+	}
+
+	expected := make([]engine.ZMember, 0, len(scores))
+	for member, score := range scores {
+		expected = append(expected, engine.ZMember{Member: member, Score: score})
+	}
+	slices.SortFunc(expected, func(a, b engine.ZMember) int {
+		return cmp.Or(cmp.Compare(a.Score, b.Score), cmp.Compare(a.Member, b.Member))
+	})
+
+	assert.Equal(t, len(expected), zset.Len())
+	assert.Equal(t, expected, zset.Range(0, -1))
+}
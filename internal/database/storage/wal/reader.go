@@ -3,6 +3,7 @@ package wal
 import (
 	"bufio"
 	"concurrency_hw/internal/config"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -98,6 +99,32 @@ func (r *StringSegmentReader) ForEach(f func(string) error) error {
 	return nil
 }
 
+// SegmentFile - файл сегмента и его номер. Номера растут в порядке записи
+type SegmentFile struct {
+	Num  int
+	Path string
+}
+
+// ListSegments возвращает сегменты каталога в порядке записи
+func ListSegments(dir string) ([]SegmentFile, error) {
+	segmentPaths, err := findSortedSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	segments := make([]SegmentFile, 0, len(segmentPaths))
+	for _, segmentPath := range segmentPaths {
+		num, err := getSegmentNum(segmentPath)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected file in wal directory: %s", segmentPath)
+		}
+
+		segments = append(segments, SegmentFile{Num: num, Path: segmentPath})
+	}
+
+	return segments, nil
+}
+
 func getSegmentNum(filePath string) (int, error) {
 	filename := filepath.Base(filePath)
 	ext := filepath.Ext(filename)
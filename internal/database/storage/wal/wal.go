@@ -2,7 +2,6 @@ package wal
 
 import (
 	"concurrency_hw/internal/config"
-	"concurrency_hw/internal/database/compute"
 	"errors"
 	"go.uber.org/zap"
 	"os"
@@ -12,17 +11,30 @@ import (
 
 const extension = "seg"
 
-var WalCommands = map[compute.CommandId]bool{
-	compute.SetCommandId: true,
-	compute.DelCommandId: true,
-}
-
 type Wal interface {
 	ForEach(func(string) error) error
 	Append(string) error
+	Stats() (Stats, error)
 	Close() error
 }
 
+// Observer получает статистику записи на диск: размер каждого сбрасываемого батча и время fsync
+type Observer interface {
+	ObserveFlush(batchSize int)
+	ObserveFsync(duration time.Duration)
+}
+
+// observable - SegmentWriter, умеющий сообщать время fsync
+type observable interface {
+	SetObserver(observer Observer)
+}
+
+// Stats - размер WAL на диске, записи в буфере до сброса не учитываются
+type Stats struct {
+	Segments int
+	Bytes    int64
+}
+
 type Segment struct {
 	segmentNum     int
 	file           *os.File
@@ -41,6 +53,7 @@ type SegmentedFSWal struct {
 	skipTick bool
 	ticker   *time.Ticker
 	mu       *sync.Mutex
+	observer Observer
 }
 
 func NewSegmentedFSWal(
@@ -102,6 +115,35 @@ func (s *SegmentedFSWal) Append(queryString string) error {
 	return nil
 }
 
+func (s *SegmentedFSWal) Stats() (Stats, error) {
+	segments, err := ListSegments(s.conf.DataDirectory)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{Segments: len(segments)}
+	for _, segment := range segments {
+		info, err := os.Stat(segment.Path)
+		if err != nil {
+			return Stats{}, err
+		}
+		stats.Bytes += info.Size()
+	}
+
+	return stats, nil
+}
+
+// SetObserver подключает наблюдателя к WAL и его writer. nil отключает наблюдение
+func (s *SegmentedFSWal) SetObserver(observer Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.observer = observer
+	if writer, ok := s.writer.(observable); ok {
+		writer.SetObserver(observer)
+	}
+}
+
 func (s *SegmentedFSWal) Close() error {
 	s.ticker.Stop()
 
@@ -118,6 +160,10 @@ func (s *SegmentedFSWal) flush() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if s.observer != nil && len(s.buff) > 0 {
+		s.observer.ObserveFlush(len(s.buff))
+	}
+
 	err := s.writer.Write(s.buff)
 	if err != nil {
 		return err
@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 type StringSegmentWriter struct {
-	conf    *config.WalConfig
-	segment *Segment
+	conf     *config.WalConfig
+	segment  *Segment
+	observer Observer
 }
 
 type SegmentWriter interface {
@@ -25,6 +27,11 @@ func NewStringSegmentWriter(conf *config.WalConfig, segment *Segment) (*StringSe
 	}, nil
 }
 
+// SetObserver вызывается до первой записи либо под той же блокировкой, что и Write
+func (w *StringSegmentWriter) SetObserver(observer Observer) {
+	w.observer = observer
+}
+
 func (w *StringSegmentWriter) Write(buff []string) error {
 	maxSegmentSize := w.conf.GetMaxSegmentSize()
 	var idx int
@@ -61,10 +68,14 @@ func (w *StringSegmentWriter) Write(buff []string) error {
 		return err
 	}
 
+	syncStart := time.Now()
 	err = w.segment.file.Sync()
 	if err != nil {
 		return err
 	}
+	if w.observer != nil {
+		w.observer.ObserveFsync(time.Since(syncStart))
+	}
 
 	return w.writeRemains(tail)
 }
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/engine"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	withScoresOption = "WITHSCORES"
+	// exclusivePrefix - граница ZRANGEBYSCORE вида "(1.5" не входит в диапазон
+	exclusivePrefix = "("
+)
+
+var errInvalidScore = errors.New("score must be a number")
+
+// zsetWrite выполняет ZADD и ZREM. Запрос пишется в WAL под блокировкой ключа через walCommit
+func (d *keyspace) zsetWrite(query compute.Query, timings *Timings) (network.Response, error) {
+	count, err := d.updateZSet(query, d.walCommit(timings))
+
+	if errors.Is(err, errInvalidScore) {
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+
+	event := KeyEventZAdd
+	if query.CommandId == compute.ZRemCommandId {
+		event = KeyEventZRem
+	}
+	d.notify(true, query.Args[0], event)
+
+	return network.ValueResponse(strconv.Itoa(count)), nil
+}
+
+// updateZSet применяет ZADD или ZREM и возвращает число добавленных или удаленных элементов.
+// ZADD меняет оценку существующих элементов, но считает только новые. commit == nil при восстановлении из WAL
+func (d *keyspace) updateZSet(query compute.Query, commit func(compute.Query) error) (int, error) {
+	key, args := query.Args[0], query.Args[1:]
+
+	var scores []float64
+	if query.CommandId == compute.ZAddCommandId {
+		for i := 0; i+1 < len(args); i += 2 {
+			score, err := parseScore(args[i])
+			if err != nil {
+				return 0, err
+			}
+			scores = append(scores, score)
+		}
+	}
+
+	count := 0
+	err := d.engine.Update(key, func(value engine.Value) (engine.Value, error) {
+		if err := checkType(value, engine.TypeZSet); err != nil {
+			return value, err
+		}
+		if value.Type == engine.TypeNone {
+			value = engine.Value{Type: engine.TypeZSet, ZSet: engine.NewZSet()}
+		}
+
+		if commit != nil {
+			if err := commit(query); err != nil {
+				return value, err
+			}
+		}
+
+		if query.CommandId == compute.ZAddCommandId {
+			for i, score := range scores {
+				if value.ZSet.Add(args[2*i+1], score) {
+					count++
+				}
+			}
+		} else {
+			for _, member := range args {
+				if value.ZSet.Remove(member) {
+					count++
+				}
+			}
+		}
+
+		if value.ZSet.Len() == 0 {
+			return engine.Value{}, nil
+		}
+		return value, nil
+	})
+
+	return count, err
+}
+
+// zsetRead выполняет ZSCORE, ZRANGE и ZRANGEBYSCORE
+func (d *keyspace) zsetRead(query compute.Query) (network.Response, error) {
+	if query.CommandId == compute.ZScoreCommandId {
+		return d.zscore(query.Args[0], query.Args[1])
+	}
+
+	selectMembers, withScores, err := parseZRange(query)
+	if err != nil {
+		return network.ErrorResponse(network.StatusInvalidArgument, err), err
+	}
+
+	var members []engine.ZMember
+	err = d.engine.View(query.Args[0], func(value engine.Value) error {
+		if err := checkType(value, engine.TypeZSet); err != nil {
+			return err
+		}
+		if value.ZSet != nil {
+			members = selectMembers(value.ZSet)
+		}
+		return nil
+	})
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+
+	values := make([]string, 0, 2*len(members))
+	for _, member := range members {
+		values = append(values, member.Member)
+		if withScores {
+			values = append(values, formatScore(member.Score))
+		}
+	}
+	return network.ValuesResponse(toValues(values)), nil
+}
+
+func (d *keyspace) zscore(key, member string) (network.Response, error) {
+	response := network.NotFoundResponse()
+	err := d.engine.View(key, func(value engine.Value) error {
+		if err := checkType(value, engine.TypeZSet); err != nil {
+			return err
+		}
+		if value.ZSet == nil {
+			return nil
+		}
+		if score, exists := value.ZSet.Score(member); exists {
+			response = network.ValueResponse(formatScore(score))
+		}
+		return nil
+	})
+
+	if err != nil {
+		return typedErrorResponse(err), err
+	}
+	return response, nil
+}
+
+// parseZRange разбирает аргументы ZRANGE и ZRANGEBYSCORE и возвращает выборку элементов
+func parseZRange(query compute.Query) (func(*engine.ZSet) []engine.ZMember, bool, error) {
+	args := query.Args
+	withScores := false
+	offset, count := 0, -1
+
+	for i := 3; i < len(args); i++ {
+		switch {
+		case args[i] == withScoresOption:
+			withScores = true
+		case args[i] == limitOption && query.CommandId == compute.ZRangeByScoreCommandId && i+2 < len(args):
+			var err error
+			offset, err = strconv.Atoi(args[i+1])
+			if err == nil {
+				count, err = strconv.Atoi(args[i+2])
+			}
+			if err != nil || offset < 0 {
+				return nil, false, errors.New("LIMIT offset must be a non-negative integer and count an integer")
+			}
+			i += 2
+		default:
+			return nil, false, fmt.Errorf("unknown option: %s", args[i])
+		}
+	}
+
+	if query.CommandId == compute.ZRangeCommandId {
+		start, err := strconv.Atoi(args[1])
+		if err != nil {
+			return nil, false, errors.New("start and stop must be integers")
+		}
+		stop, err := strconv.Atoi(args[2])
+		if err != nil {
+			return nil, false, errors.New("start and stop must be integers")
+		}
+
+		return func(zset *engine.ZSet) []engine.ZMember {
+			return zset.Range(start, stop)
+		}, withScores, nil
+	}
+
+	lower, err := parseScoreBound(args[1])
+	if err != nil {
+		return nil, false, err
+	}
+	upper, err := parseScoreBound(args[2])
+	if err != nil {
+		return nil, false, err
+	}
+
+	return func(zset *engine.ZSet) []engine.ZMember {
+		return zset.RangeByScore(lower, upper, offset, count)
+	}, withScores, nil
+}
+
+func parseScore(value string) (float64, error) {
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, fmt.Errorf("%w: %s", errInvalidScore, value)
+	}
+	return score, nil
+}
+
+// parseScoreBound разбирает границу ZRANGEBYSCORE: число, -inf, +inf или "(" и число для исключающей границы
+func parseScoreBound(value string) (engine.ScoreBound, error) {
+	var bound engine.ScoreBound
+	if rest, found := strings.CutPrefix(value, exclusivePrefix); found {
+		bound.Exclusive = true
+		value = rest
+	}
+
+	score, err := parseScore(value)
+	if err != nil {
+		return bound, err
+	}
+	bound.Score = score
+	return bound, nil
+}
+
+// formatScore записывает оценку кратчайшим точным представлением, бесконечности - как inf и -inf
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	default:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+}
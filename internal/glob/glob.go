@@ -0,0 +1,104 @@
+package glob
+
+// Match проверяет строку на соответствие glob-шаблону в стиле redis:
+// * - любая последовательность символов, ? - один символ, [abc], [a-z], [^a] - классы символов, \ - экранирование
+func Match(pattern, str string) bool {
+	p, s := 0, 0
+	starP, starS := -1, -1
+
+	for s < len(str) {
+		if p < len(pattern) {
+			switch pattern[p] {
+			case '*':
+				starP, starS = p, s
+				p++
+				continue
+			case '?':
+				p++
+				s++
+				continue
+			case '[':
+				if matched, next, ok := matchClass(pattern, p, str[s]); ok {
+					if matched {
+						p = next
+						s++
+						continue
+					}
+				} else if str[s] == '[' {
+					p++
+					s++
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) && pattern[p+1] == str[s] {
+					p += 2
+					s++
+					continue
+				}
+			default:
+				if pattern[p] == str[s] {
+					p++
+					s++
+					continue
+				}
+			}
+		}
+
+		if starP < 0 {
+			return false
+		}
+
+		// Откатываемся к последней звездочке и расширяем ее на один символ
+		starS++
+		p, s = starP+1, starS
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+
+	return p == len(pattern)
+}
+
+// matchClass разбирает класс символов, начинающийся с pattern[start] == '['.
+// ok == false, если класс не закрыт и '[' надо трактовать как обычный символ
+func matchClass(pattern string, start int, c byte) (matched bool, next int, ok bool) {
+	i := start + 1
+	negate := false
+	if i < len(pattern) && pattern[i] == '^' {
+		negate = true
+		i++
+	}
+
+	first := true
+	for i < len(pattern) && (first || pattern[i] != ']') {
+		first = false
+
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+		hi := lo
+
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			hi = pattern[i+2]
+			if hi == '\\' && i+3 < len(pattern) {
+				i++
+				hi = pattern[i+2]
+			}
+			i += 2
+		}
+
+		if lo <= c && c <= hi {
+			matched = true
+		}
+		i++
+	}
+
+	if i >= len(pattern) {
+		return false, 0, false
+	}
+
+	return matched != negate, i + 1, true
+}
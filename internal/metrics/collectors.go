@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"concurrency_hw/internal/database"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/database/storage/wal"
+	"time"
+)
+
+const namespace = "condb_"
+
+// BatchSizeBuckets - границы корзин для числа запросов в одном сбросе WAL
+var BatchSizeBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// DatabaseMetrics реализует database.Observer
+type DatabaseMetrics struct {
+	commands *CounterVec
+	parse    *Histogram
+	wal      *Histogram
+	apply    *Histogram
+}
+
+func NewDatabaseMetrics(registry *Registry) *DatabaseMetrics {
+	phases := registry.Histogram(namespace+"query_phase_duration_seconds",
+		"Duration of query execution phases: parse, wal append and engine apply.", DurationBuckets, "phase")
+
+	return &DatabaseMetrics{
+		commands: registry.Counter(namespace+"commands_total", "Executed commands by command and result status.",
+			"command", "status"),
+		parse: phases.With("parse"),
+		wal:   phases.With("wal"),
+		apply: phases.With("apply"),
+	}
+}
+
+// ObserveQuery пропускает фазы, которых у запроса не было
+func (m *DatabaseMetrics) ObserveQuery(command string, status network.StatusCode, timings database.Timings) {
+	if command == "" {
+		command = "unknown"
+	}
+	m.commands.With(command, status.String()).Inc()
+
+	m.parse.ObserveDuration(timings.Parse)
+	if timings.Wal > 0 {
+		m.wal.ObserveDuration(timings.Wal)
+	}
+	if timings.Apply > 0 {
+		m.apply.ObserveDuration(timings.Apply)
+	}
+}
+
+// WalMetrics реализует wal.Observer
+type WalMetrics struct {
+	batchSize *Histogram
+	fsync     *Histogram
+}
+
+func NewWalMetrics(registry *Registry) *WalMetrics {
+	return &WalMetrics{
+		batchSize: registry.Histogram(namespace+"wal_flush_batch_size", "Number of queries written by one WAL flush.",
+			BatchSizeBuckets).With(),
+		fsync: registry.Histogram(namespace+"wal_fsync_duration_seconds", "Duration of WAL segment fsync.",
+			DurationBuckets).With(),
+	}
+}
+
+func (m *WalMetrics) ObserveFlush(batchSize int) {
+	m.batchSize.Observe(float64(batchSize))
+}
+
+func (m *WalMetrics) ObserveFsync(duration time.Duration) {
+	m.fsync.ObserveDuration(duration)
+}
+
+// RegisterStorage регистрирует число ключей во всех базах и размер WAL на диске
+func RegisterStorage(registry *Registry, engine interface{ Len() int }, walStats interface {
+	Stats() (wal.Stats, error)
+}) {
+	registry.GaugeFunc(namespace+"keys", "Number of keys in all databases.", nil, func() []Sample {
+		return []Sample{Value(float64(engine.Len()))}
+	})
+
+	collectWal := func(value func(wal.Stats) float64) func() []Sample {
+		return func() []Sample {
+			stats, err := walStats.Stats()
+			if err != nil {
+				return nil
+			}
+			return []Sample{Value(value(stats))}
+		}
+	}
+
+	registry.GaugeFunc(namespace+"wal_segments", "Number of WAL segments on disk.", nil,
+		collectWal(func(stats wal.Stats) float64 { return float64(stats.Segments) }))
+	registry.GaugeFunc(namespace+"wal_bytes", "Size of WAL segments on disk in bytes.", nil,
+		collectWal(func(stats wal.Stats) float64 { return float64(stats.Bytes) }))
+}
+
+// RegisterConnections регистрирует открытые соединения tcp и http и число tcp-соединений с момента запуска
+func RegisterConnections(registry *Registry, limiter *network.ConnectionLimiter, clients *network.ClientRegistry) {
+	registry.GaugeFunc(namespace+"connections_active", "Open tcp and http connections.", nil, func() []Sample {
+		return []Sample{Value(float64(limiter.Active()))}
+	})
+	registry.CounterFunc(namespace+"connections_total", "Accepted tcp connections.", nil, func() []Sample {
+		return []Sample{Value(float64(clients.Total()))}
+	})
+}
+
+// RegisterTCPServer регистрирует таймауты и слишком большие сообщения tcp-сервера
+func RegisterTCPServer(registry *Registry, server *network.TCPServer) {
+	registry.CounterFunc(namespace+"timeouts_total", "Closed connections and requests by timeout kind.",
+		[]string{"kind"}, func() []Sample {
+			return samples(server.Timeouts())
+		})
+	registry.CounterFunc(namespace+"tcp_oversized_messages_total", "Tcp messages over max_message_size by direction.",
+		[]string{"direction"}, func() []Sample {
+			return samples(server.Oversized())
+		})
+}
+
+func RegisterHTTPServer(registry *Registry, server *network.HTTPServer) {
+	registry.CounterFunc(namespace+"http_oversized_messages_total", "Http messages over max_message_size by direction.",
+		[]string{"direction"}, func() []Sample {
+			return samples(server.Oversized())
+		})
+}
+
+func RegisterRateLimiter(registry *Registry, limiter *network.RateLimiter) {
+	registry.CounterFunc(namespace+"throttled_requests_total", "Rate limited requests by limit scope and action.",
+		[]string{"scope", "action"}, func() []Sample {
+			var result []Sample
+			for scope, stats := range limiter.Stats() {
+				result = append(result,
+					Value(float64(stats.Delayed), scope, "delayed"),
+					Value(float64(stats.Rejected), scope, "rejected"),
+				)
+			}
+			return result
+		})
+}
+
+func samples(counters map[string]uint64) []Sample {
+	result := make([]Sample, 0, len(counters))
+	for label, value := range counters {
+		result = append(result, Value(float64(value), label))
+	}
+	return result
+}
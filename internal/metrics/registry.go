@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Registry - набор метрик, отдаваемых в текстовом формате Prometheus
+type Registry struct {
+	mu      sync.RWMutex
+	metrics map[string]metric
+}
+
+type metric interface {
+	write(w *bufio.Writer, name string)
+}
+
+type description struct {
+	help   string
+	kind   string
+	labels []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		metrics: make(map[string]metric),
+	}
+}
+
+// Counter регистрирует счетчик с метками labels. Повторная регистрация имени - ошибка программиста
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	counter := &CounterVec{
+		description: description{help: help, kind: "counter", labels: labels},
+		children:    make(map[string]*Counter),
+	}
+	r.register(name, counter)
+	return counter
+}
+
+// Histogram регистрирует гистограмму с верхними границами корзин buckets
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	histogram := &HistogramVec{
+		description: description{help: help, kind: "histogram", labels: labels},
+		buckets:     slices.Sorted(slices.Values(buckets)),
+		children:    make(map[string]*Histogram),
+	}
+	r.register(name, histogram)
+	return histogram
+}
+
+// CounterFunc и GaugeFunc регистрируют метрики, значения которых собираются при каждом запросе /metrics
+func (r *Registry) CounterFunc(name, help string, labels []string, collect func() []Sample) {
+	r.register(name, &funcMetric{
+		description: description{help: help, kind: "counter", labels: labels},
+		collect:     collect,
+	})
+}
+
+func (r *Registry) GaugeFunc(name, help string, labels []string, collect func() []Sample) {
+	r.register(name, &funcMetric{
+		description: description{help: help, kind: "gauge", labels: labels},
+		collect:     collect,
+	})
+}
+
+func (r *Registry) register(name string, m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.metrics[name]; exists {
+		panic(fmt.Sprintf("metric %s is already registered", name))
+	}
+	r.metrics[name] = m
+}
+
+// Write пишет все метрики в порядке имен
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]metric, 0, len(names))
+	slices.Sort(names)
+	for _, name := range names {
+		metrics = append(metrics, r.metrics[name])
+	}
+	r.mu.RUnlock()
+
+	writer := bufio.NewWriter(w)
+	for i, m := range metrics {
+		m.write(writer, names[i])
+	}
+
+	return writer.Flush()
+}
+
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_ = r.Write(w)
+}
+
+// Sample - значение метрики для набора значений меток
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+func Value(value float64, labelValues ...string) Sample {
+	return Sample{LabelValues: labelValues, Value: value}
+}
+
+type funcMetric struct {
+	description
+	collect func() []Sample
+}
+
+func (m *funcMetric) write(w *bufio.Writer, name string) {
+	m.header(w, name)
+
+	samples := m.collect()
+	slices.SortFunc(samples, func(a, b Sample) int {
+		return slices.Compare(a.LabelValues, b.LabelValues)
+	})
+	for _, sample := range samples {
+		writeSample(w, name, m.labels, sample.LabelValues, "", sample.Value)
+	}
+}
+
+func (d description) header(w *bufio.Writer, name string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.ReplaceAll(d.help, "\n", " "))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, d.kind)
+}
+
+// writeSample пишет строку "name{label="value",...} value". extra - дополнительная метка в готовом виде, например le
+func writeSample(w *bufio.Writer, name string, labels, values []string, extra string, value float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 || extra != "" {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escapeLabel(values[i]) + `"`)
+		}
+		if extra != "" {
+			if len(labels) > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(extra)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,114 @@
+//go:build unit
+
+package metrics_test
+
+import (
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/creator"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRegistry_Write(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	commands := registry.Counter("test_commands_total", "Commands.", "command", "status")
+	commands.With("SET", "ok").Inc()
+	commands.With("SET", "ok").Add(2)
+	commands.With("GET", `say "hi"`).Inc()
+
+	latency := registry.Histogram("test_latency_seconds", "Latency.", []float64{0.1, 1}, "phase")
+	latency.With("parse").Observe(0.05)
+	latency.With("parse").Observe(0.1)
+	latency.With("parse").ObserveDuration(2 * time.Second)
+
+	registry.GaugeFunc("test_keys", "Keys.", nil, func() []metrics.Sample {
+		return []metrics.Sample{metrics.Value(42)}
+	})
+
+	var builder strings.Builder
+	require.NoError(t, registry.Write(&builder))
+
+	expected := `# HELP test_commands_total Commands.
+# TYPE test_commands_total counter
+test_commands_total{command="GET",status="say \"hi\""} 1
+test_commands_total{command="SET",status="ok"} 3
+# HELP test_keys Keys.
+# TYPE test_keys gauge
+test_keys 42
+# HELP test_latency_seconds Latency.
+# TYPE test_latency_seconds histogram
+test_latency_seconds_bucket{phase="parse",le="0.1"} 2
+test_latency_seconds_bucket{phase="parse",le="1"} 2
+test_latency_seconds_bucket{phase="parse",le="+Inf"} 3
+test_latency_seconds_sum{phase="parse"} 2.15
+test_latency_seconds_count{phase="parse"} 3
+`
+	assert.Equal(t, expected, builder.String())
+
+	assert.Panics(t, func() {
+		registry.Counter("test_keys", "Duplicate.")
+	})
+}
+
+func TestServer_Metrics(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	conf := config.Load()
+	conf.WalConfig.DataDirectory = t.TempDir()
+	conf.WalConfig.FlushingBatchSize = 1
+	conf.MetricsConfig = &config.MetricsConfig{Address: "127.0.0.1:0"}
+
+	initializer := creator.NewCreator(logger, conf)
+	db, err := initializer.CreateDatabase()
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Stop())
+	}()
+
+	session := network.NewSession("127.0.0.1:5000")
+	for _, query := range []string{"SET a 1", "GET a", "GET b", "FOO"} {
+		_, _ = db.Execute(session, query)
+	}
+
+	server, err := metrics.NewServer(logger, initializer.Metrics())
+	require.NoError(t, err)
+
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/metrics")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	for _, line := range []string{
+		`condb_commands_total{command="SET",status="ok"} 1`,
+		`condb_commands_total{command="GET",status="ok"} 1`,
+		`condb_commands_total{command="GET",status="not_found"} 1`,
+		`condb_commands_total{command="unknown",status="parse_error"} 1`,
+		`condb_query_phase_duration_seconds_count{phase="parse"} 4`,
+		`condb_query_phase_duration_seconds_count{phase="wal"} 1`,
+		`condb_query_phase_duration_seconds_count{phase="apply"} 3`,
+		`condb_wal_flush_batch_size_count 1`,
+		`condb_wal_fsync_duration_seconds_count 1`,
+		`condb_wal_segments 1`,
+		`condb_keys 1`,
+	} {
+		assert.Contains(t, string(body), line+"\n")
+	}
+}
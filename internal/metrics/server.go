@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const readHeaderTimeout = 5 * time.Second
+
+// Server отдает метрики реестра по GET /metrics
+type Server struct {
+	logger   *zap.Logger
+	registry *Registry
+}
+
+func NewServer(logger *zap.Logger, registry *Registry) (*Server, error) {
+	if logger == nil {
+		return nil, errors.New("logger cannot be nil")
+	}
+
+	if registry == nil {
+		return nil, errors.New("registry cannot be nil")
+	}
+
+	return &Server{
+		logger:   logger,
+		registry: registry,
+	}, nil
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", s.registry)
+	return mux
+}
+
+func (s *Server) Run(ctx context.Context, address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	stopClosing := context.AfterFunc(ctx, func() {
+		_ = server.Close()
+	})
+	defer stopClosing()
+
+	s.logger.Info("metrics server listening on " + listener.Addr().String())
+
+	err = server.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
+}
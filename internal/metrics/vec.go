@@ -0,0 +1,151 @@
+package metrics
+
+import (
+	"bufio"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DurationBuckets - границы корзин в секундах для задержек от 100мкс до 1с
+var DurationBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+type CounterVec struct {
+	description
+	mu       sync.RWMutex
+	children map[string]*Counter
+}
+
+type Counter struct {
+	labelValues []string
+	value       atomic.Uint64
+}
+
+// With возвращает счетчик для значений меток в порядке их объявления
+func (v *CounterVec) With(labelValues ...string) *Counter {
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	counter, exists := v.children[key]
+	v.mu.RUnlock()
+	if exists {
+		return counter
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if counter, exists = v.children[key]; !exists {
+		counter = &Counter{labelValues: slices.Clone(labelValues)}
+		v.children[key] = counter
+	}
+	return counter
+}
+
+func (c *Counter) Inc() {
+	c.value.Add(1)
+}
+
+func (c *Counter) Add(delta uint64) {
+	c.value.Add(delta)
+}
+
+func (v *CounterVec) write(w *bufio.Writer, name string) {
+	v.header(w, name)
+
+	for _, counter := range sortedChildren(&v.mu, v.children, func(c *Counter) []string { return c.labelValues }) {
+		writeSample(w, name, v.labels, counter.labelValues, "", float64(counter.value.Load()))
+	}
+}
+
+type HistogramVec struct {
+	description
+	buckets  []float64
+	mu       sync.RWMutex
+	children map[string]*Histogram
+}
+
+type Histogram struct {
+	labelValues []string
+	buckets     []float64
+	counts      []atomic.Uint64
+	count       atomic.Uint64
+	// sum хранит биты float64, чтобы обновлять его без блокировки
+	sum atomic.Uint64
+}
+
+func (v *HistogramVec) With(labelValues ...string) *Histogram {
+	key := strings.Join(labelValues, "\xff")
+
+	v.mu.RLock()
+	histogram, exists := v.children[key]
+	v.mu.RUnlock()
+	if exists {
+		return histogram
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if histogram, exists = v.children[key]; !exists {
+		histogram = &Histogram{
+			labelValues: slices.Clone(labelValues),
+			buckets:     v.buckets,
+			counts:      make([]atomic.Uint64, len(v.buckets)),
+		}
+		v.children[key] = histogram
+	}
+	return histogram
+}
+
+func (h *Histogram) Observe(value float64) {
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.counts) {
+		h.counts[i].Add(1)
+	}
+	h.count.Add(1)
+
+	for {
+		old := h.sum.Load()
+		if h.sum.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+value)) {
+			return
+		}
+	}
+}
+
+func (h *Histogram) ObserveDuration(duration time.Duration) {
+	h.Observe(duration.Seconds())
+}
+
+func (v *HistogramVec) write(w *bufio.Writer, name string) {
+	v.header(w, name)
+
+	for _, histogram := range sortedChildren(&v.mu, v.children, func(h *Histogram) []string { return h.labelValues }) {
+		var cumulative uint64
+		for i, bound := range histogram.buckets {
+			cumulative += histogram.counts[i].Load()
+			writeSample(w, name+"_bucket", v.labels, histogram.labelValues, `le="`+formatFloat(bound)+`"`, float64(cumulative))
+		}
+
+		count := histogram.count.Load()
+		writeSample(w, name+"_bucket", v.labels, histogram.labelValues, `le="+Inf"`, float64(count))
+		writeSample(w, name+"_sum", v.labels, histogram.labelValues, "", math.Float64frombits(histogram.sum.Load()))
+		writeSample(w, name+"_count", v.labels, histogram.labelValues, "", float64(count))
+	}
+}
+
+func sortedChildren[T any](mu *sync.RWMutex, children map[string]*T, labelValues func(*T) []string) []*T {
+	mu.RLock()
+	sorted := make([]*T, 0, len(children))
+	for _, child := range children {
+		sorted = append(sorted, child)
+	}
+	mu.RUnlock()
+
+	slices.SortFunc(sorted, func(a, b *T) int {
+		return slices.Compare(labelValues(a), labelValues(b))
+	})
+	return sorted
+}
@@ -0,0 +1,467 @@
+// Package client - клиент БД для приложений: пул соединений, автоматическое переподключение,
+// повтор идемпотентных команд с backoff, дедлайны на каждый вызов и типизированные ошибки
+package client
+
+import (
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"time"
+)
+
+type Options struct {
+	Address string
+	// PoolSize - максимальное число одновременно открытых соединений
+	PoolSize    int
+	DialTimeout time.Duration
+	// CallTimeout - дедлайн одного запроса, если в контексте не задан более ранний
+	CallTimeout time.Duration
+	// MaxRetries - число повторов идемпотентных команд после первой попытки
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Username   string
+	Password   string
+}
+
+func (o Options) withDefaults() Options {
+	if o.PoolSize <= 0 {
+		o.PoolSize = 10
+	}
+	if o.DialTimeout <= 0 {
+		o.DialTimeout = time.Second
+	}
+	if o.CallTimeout <= 0 {
+		o.CallTimeout = 5 * time.Second
+	}
+	if o.MaxRetries < 0 {
+		o.MaxRetries = 0
+	}
+	if o.MinBackoff <= 0 {
+		o.MinBackoff = 10 * time.Millisecond
+	}
+	if o.MaxBackoff < o.MinBackoff {
+		o.MaxBackoff = max(time.Second, o.MinBackoff)
+	}
+	return o
+}
+
+type Client struct {
+	opts Options
+	pool *pool
+}
+
+func New(opts Options) (*Client, error) {
+	if opts.Address == "" {
+		return nil, errors.New("address cannot be empty")
+	}
+
+	opts = opts.withDefaults()
+
+	return &Client{
+		opts: opts,
+		pool: newPool(opts),
+	}, nil
+}
+
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	if err := validateKeys(key); err != nil {
+		return "", err
+	}
+
+	response, err := c.do(ctx, query(compute.GetCommandId, key), true)
+	if err != nil {
+		return "", err
+	}
+
+	return *response.Value, nil
+}
+
+func (c *Client) Set(ctx context.Context, key, value string) error {
+	if err := validateKeys(key); err != nil {
+		return err
+	}
+
+	_, err := c.do(ctx, query(compute.SetCommandId, key, value), true)
+	return err
+}
+
+func (c *Client) Del(ctx context.Context, key string) error {
+	if err := validateKeys(key); err != nil {
+		return err
+	}
+
+	_, err := c.do(ctx, query(compute.DelCommandId, key), true)
+	return err
+}
+
+// MGet возвращает значения ключей в том же порядке, nil - ключ отсутствует
+func (c *Client) MGet(ctx context.Context, keys ...string) ([]*string, error) {
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: at least one key is required", ErrInvalidArgument)
+	}
+	if err := validateKeys(keys...); err != nil {
+		return nil, err
+	}
+
+	response, err := c.do(ctx, query(compute.MGetCommandId, keys...), true)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Values, nil
+}
+
+// MSet атомарно записывает все пары ключ-значение
+func (c *Client) MSet(ctx context.Context, values map[string]string) error {
+	if len(values) == 0 {
+		return fmt.Errorf("%w: at least one key is required", ErrInvalidArgument)
+	}
+
+	args := make([]string, 0, 2*len(values))
+	for key, value := range values {
+		if err := validateKeys(key); err != nil {
+			return err
+		}
+		args = append(args, key, value)
+	}
+
+	_, err := c.do(ctx, query(compute.MSetCommandId, args...), true)
+	return err
+}
+
+// MDel удаляет ключи и возвращает число удаленных
+func (c *Client) MDel(ctx context.Context, keys ...string) (int, error) {
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("%w: at least one key is required", ErrInvalidArgument)
+	}
+	if err := validateKeys(keys...); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.MDelCommandId, keys...), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(*response.Value)
+}
+
+// IncrBy атомарно прибавляет delta к целому значению ключа на сервере и возвращает результат.
+// Не повторяется при обрыве соединения, чтобы не прибавить дважды
+func (c *Client) IncrBy(ctx context.Context, key string, delta int64) (int64, error) {
+	if err := validateKeys(key); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.IncrByCommandId, key, strconv.FormatInt(delta, 10)), false)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseInt(*response.Value, 10, 64)
+}
+
+func (c *Client) Incr(ctx context.Context, key string) (int64, error) {
+	return c.IncrBy(ctx, key, 1)
+}
+
+// HSet записывает поля хеша и возвращает число новых полей
+func (c *Client) HSet(ctx context.Context, key string, fields map[string]string) (int, error) {
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: at least one field is required", ErrInvalidArgument)
+	}
+
+	args := make([]string, 0, 2*len(fields))
+	for field, value := range fields {
+		args = append(args, field, value)
+	}
+	if err := validateKeys(key); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.HSetCommandId, append([]string{key}, args...)...), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(*response.Value)
+}
+
+// HGet возвращает значение поля хеша или ErrNotFound
+func (c *Client) HGet(ctx context.Context, key, field string) (string, error) {
+	if err := validateKeys(key); err != nil {
+		return "", err
+	}
+
+	response, err := c.do(ctx, query(compute.HGetCommandId, key, field), true)
+	if err != nil {
+		return "", err
+	}
+
+	return *response.Value, nil
+}
+
+// HGetAll возвращает все поля хеша, для отсутствующего ключа - пустую map
+func (c *Client) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	if err := validateKeys(key); err != nil {
+		return nil, err
+	}
+
+	response, err := c.do(ctx, query(compute.HGetAllCommandId, key), true)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string, len(response.Values)/2)
+	for i := 0; i+1 < len(response.Values); i += 2 {
+		if response.Values[i] != nil && response.Values[i+1] != nil {
+			fields[*response.Values[i]] = *response.Values[i+1]
+		}
+	}
+	return fields, nil
+}
+
+// HDel удаляет поля хеша и возвращает число удаленных
+func (c *Client) HDel(ctx context.Context, key string, fields ...string) (int, error) {
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("%w: at least one field is required", ErrInvalidArgument)
+	}
+	if err := validateKeys(key); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.HDelCommandId, append([]string{key}, fields...)...), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(*response.Value)
+}
+
+// LPush добавляет значения в голову списка и возвращает его длину. Не повторяется при обрыве соединения
+func (c *Client) LPush(ctx context.Context, key string, values ...string) (int, error) {
+	return c.push(ctx, compute.LPushCommandId, key, values)
+}
+
+// RPush добавляет значения в хвост списка и возвращает его длину. Не повторяется при обрыве соединения
+func (c *Client) RPush(ctx context.Context, key string, values ...string) (int, error) {
+	return c.push(ctx, compute.RPushCommandId, key, values)
+}
+
+func (c *Client) push(ctx context.Context, command compute.CommandId, key string, values []string) (int, error) {
+	if len(values) == 0 {
+		return 0, fmt.Errorf("%w: at least one value is required", ErrInvalidArgument)
+	}
+	if err := validateKeys(key); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(command, append([]string{key}, values...)...), false)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(*response.Value)
+}
+
+// LPop снимает элемент с головы списка, для пустого списка возвращает ErrNotFound.
+// Не повторяется при обрыве соединения, чтобы не потерять элемент
+func (c *Client) LPop(ctx context.Context, key string) (string, error) {
+	if err := validateKeys(key); err != nil {
+		return "", err
+	}
+
+	response, err := c.do(ctx, query(compute.LPopCommandId, key), false)
+	if err != nil {
+		return "", err
+	}
+
+	return *response.Value, nil
+}
+
+// SAdd добавляет элементы в множество и возвращает число новых
+func (c *Client) SAdd(ctx context.Context, key string, members ...string) (int, error) {
+	if len(members) == 0 {
+		return 0, fmt.Errorf("%w: at least one member is required", ErrInvalidArgument)
+	}
+	if err := validateKeys(key); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.SAddCommandId, append([]string{key}, members...)...), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(*response.Value)
+}
+
+// SMembers возвращает элементы множества по возрастанию
+func (c *Client) SMembers(ctx context.Context, key string) ([]string, error) {
+	if err := validateKeys(key); err != nil {
+		return nil, err
+	}
+
+	response, err := c.do(ctx, query(compute.SMembersCommandId, key), true)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]string, 0, len(response.Values))
+	for _, member := range response.Values {
+		if member != nil {
+			members = append(members, *member)
+		}
+	}
+	return members, nil
+}
+
+// ZAdd добавляет элемент в сортированное множество или меняет его оценку. Возвращает true для нового элемента
+func (c *Client) ZAdd(ctx context.Context, key string, score float64, member string) (bool, error) {
+	if err := validateKeys(key); err != nil {
+		return false, err
+	}
+
+	response, err := c.do(ctx, query(compute.ZAddCommandId, key, strconv.FormatFloat(score, 'g', -1, 64), member), true)
+	if err != nil {
+		return false, err
+	}
+
+	return response.Value != nil && *response.Value == "1", nil
+}
+
+// ZScore возвращает оценку элемента или ErrNotFound
+func (c *Client) ZScore(ctx context.Context, key, member string) (float64, error) {
+	if err := validateKeys(key); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.ZScoreCommandId, key, member), true)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseFloat(*response.Value, 64)
+}
+
+// Publish отправляет сообщение в канал и возвращает число получивших его подписчиков
+func (c *Client) Publish(ctx context.Context, channel, message string) (int, error) {
+	if err := validateKeys(channel); err != nil {
+		return 0, err
+	}
+
+	response, err := c.do(ctx, query(compute.PublishCommandId, channel, message), false)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.Atoi(*response.Value)
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.do(ctx, "PING", true)
+	return err
+}
+
+// Response - ответ сервера на запрос Do. Value == nil - у команды нет значения, Values - значения команд
+// с несколькими ключами, nil в них - отсутствующий ключ
+type Response struct {
+	Value  *string
+	Values []*string
+}
+
+// Do выполняет произвольный запрос. Повторяется он только если сервер гарантированно его не выполнил
+func (c *Client) Do(ctx context.Context, query string) (Response, error) {
+	response, err := c.do(ctx, query, false)
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Value: response.Value, Values: response.Values}, nil
+}
+
+func (c *Client) Close() error {
+	return c.pool.close()
+}
+
+func (c *Client) do(ctx context.Context, query string, idempotent bool) (network.Response, error) {
+	var lastErr error
+
+	for attempt := 0; attempt <= c.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := c.backoff(ctx, attempt); err != nil {
+				return network.Response{}, errors.Join(lastErr, err)
+			}
+		}
+
+		response, sent, err := c.attempt(ctx, query)
+		if err == nil {
+			err = responseError(response)
+			if err == nil || !retryableStatus(err) {
+				return response, err
+			}
+		}
+
+		lastErr = err
+
+		if ctx.Err() != nil || errors.Is(err, ErrClosed) {
+			break
+		}
+		// Неидемпотентный запрос, который мог дойти до сервера, повторять нельзя
+		if sent && !idempotent && !retryableStatus(err) {
+			break
+		}
+	}
+
+	return network.Response{}, lastErr
+}
+
+// attempt выполняет одну попытку; sent == true, если запрос мог быть отправлен серверу
+func (c *Client) attempt(ctx context.Context, query string) (network.Response, bool, error) {
+	cn, err := c.pool.get(ctx)
+	if err != nil {
+		return network.Response{}, false, err
+	}
+	defer c.pool.put(cn)
+
+	response, err := cn.roundTrip(ctx, query, c.opts.CallTimeout)
+	return response, true, err
+}
+
+// backoff - экспоненциальная задержка с jitter
+func (c *Client) backoff(ctx context.Context, attempt int) error {
+	delay := c.opts.MinBackoff << (attempt - 1)
+	if delay <= 0 || delay > c.opts.MaxBackoff {
+		delay = c.opts.MaxBackoff
+	}
+	delay = delay/2 + rand.N(delay/2+1)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// query собирает запрос с аргументами в кавычках там, где нужно: пробелы и спецсимволы в ключах и значениях
+// не меняют число аргументов
+func query(command compute.CommandId, args ...string) string {
+	return compute.Query{CommandId: command, Args: args}.String()
+}
+
+func validateKeys(keys ...string) error {
+	for _, key := range keys {
+		if key == "" {
+			return fmt.Errorf("%w: keys must be non-empty", ErrInvalidArgument)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,314 @@
+//go:build unit
+
+package client_test
+
+import (
+	"bufio"
+	"concurrency_hw/internal/config"
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"concurrency_hw/pkg/client"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient(t *testing.T) {
+	var mu sync.Mutex
+	storage := map[string]string{}
+
+	address := startServer(t, func(_ *network.Session, query string) (network.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		tokens, err := compute.Tokenize(query)
+		switch {
+		case err != nil:
+			return network.ErrorResponse(network.StatusParseError, err), err
+		case len(tokens) == 2 && strings.HasPrefix(tokens[1], "secret"):
+			err := errors.New("permission denied")
+			return network.ErrorResponse(network.StatusForbidden, err), err
+		case len(tokens) == 3 && tokens[0] == "SET":
+			storage[tokens[1]] = tokens[2]
+			return network.OKResponse(), nil
+		case len(tokens) == 2 && tokens[0] == "GET":
+			value, exists := storage[tokens[1]]
+			if !exists {
+				return network.NotFoundResponse(), nil
+			}
+			return network.ValueResponse(value), nil
+		case len(tokens) == 2 && tokens[0] == "DEL":
+			delete(storage, tokens[1])
+			return network.OKResponse(), nil
+		case len(tokens) > 1 && tokens[0] == "MGET":
+			values := make([]*string, 0, len(tokens)-1)
+			for _, key := range tokens[1:] {
+				if value, exists := storage[key]; exists {
+					values = append(values, &value)
+				} else {
+					values = append(values, nil)
+				}
+			}
+			return network.ValuesResponse(values), nil
+		case len(tokens) > 1 && len(tokens)%2 == 1 && tokens[0] == "MSET":
+			for i := 1; i < len(tokens); i += 2 {
+				storage[tokens[i]] = tokens[i+1]
+			}
+			return network.OKResponse(), nil
+		case len(tokens) > 1 && tokens[0] == "MDEL":
+			deleted := 0
+			for _, key := range tokens[1:] {
+				if _, exists := storage[key]; exists {
+					delete(storage, key)
+					deleted++
+				}
+			}
+			return network.ValueResponse(strconv.Itoa(deleted)), nil
+		case len(tokens) == 3 && tokens[0] == "INCRBY":
+			current, err := strconv.ParseInt(storage[tokens[1]], 10, 64)
+			if storage[tokens[1]] != "" && err != nil {
+				return network.ErrorResponse(network.StatusNotInteger, err), err
+			}
+			delta, _ := strconv.ParseInt(tokens[2], 10, 64)
+			storage[tokens[1]] = strconv.FormatInt(current+delta, 10)
+			return network.ValueResponse(storage[tokens[1]]), nil
+		case len(tokens) == 1 && tokens[0] == "PING":
+			return network.ValueResponse("PONG"), nil
+		default:
+			err := errors.New("invalid query")
+			return network.ErrorResponse(network.StatusParseError, err), err
+		}
+	})
+
+	cli, err := client.New(client.Options{Address: address, PoolSize: 4})
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, cli.Close())
+	}()
+
+	ctx := context.Background()
+
+	t.Run("Set, Get and Del", func(t *testing.T) {
+		require.NoError(t, cli.Set(ctx, "key", "value"))
+
+		value, err := cli.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, "value", value)
+
+		require.NoError(t, cli.Del(ctx, "key"))
+
+		_, err = cli.Get(ctx, "key")
+		assert.ErrorIs(t, err, client.ErrNotFound)
+	})
+
+	t.Run("MSet, MGet and MDel", func(t *testing.T) {
+		require.NoError(t, cli.MSet(ctx, map[string]string{"m1": "one", "m2": "two"}))
+
+		values, err := cli.MGet(ctx, "m1", "missing", "m2")
+		require.NoError(t, err)
+		require.Len(t, values, 3)
+		require.NotNil(t, values[0])
+		assert.Equal(t, "one", *values[0])
+		assert.Nil(t, values[1])
+		require.NotNil(t, values[2])
+		assert.Equal(t, "two", *values[2])
+
+		deleted, err := cli.MDel(ctx, "m1", "m2", "missing")
+		require.NoError(t, err)
+		assert.Equal(t, 2, deleted)
+
+		_, err = cli.MGet(ctx)
+		assert.ErrorIs(t, err, client.ErrInvalidArgument)
+	})
+
+	t.Run("Counters", func(t *testing.T) {
+		value, err := cli.Incr(ctx, "counter")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), value)
+
+		value, err = cli.IncrBy(ctx, "counter", -5)
+		require.NoError(t, err)
+		assert.Equal(t, int64(-4), value)
+
+		require.NoError(t, cli.Set(ctx, "text", "abc"))
+		_, err = cli.Incr(ctx, "text")
+		assert.ErrorIs(t, err, client.ErrNotInteger)
+	})
+
+	t.Run("Typed server errors", func(t *testing.T) {
+		_, err := cli.Get(ctx, "secret")
+		assert.ErrorIs(t, err, client.ErrForbidden)
+
+		var serverErr *client.ServerError
+		require.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, client.StatusForbidden, serverErr.Status)
+
+		_, err = cli.Do(ctx, "UNKNOWN")
+		assert.ErrorIs(t, err, client.ErrParse)
+		require.ErrorAs(t, err, &serverErr)
+		assert.Equal(t, client.StatusParseError, serverErr.Status)
+	})
+
+	t.Run("Do", func(t *testing.T) {
+		require.NoError(t, cli.Set(ctx, "raw", "value"))
+
+		response, err := cli.Do(ctx, "GET raw")
+		require.NoError(t, err)
+		require.NotNil(t, response.Value)
+		assert.Equal(t, "value", *response.Value)
+
+		response, err = cli.Do(ctx, "SET raw other")
+		require.NoError(t, err)
+		assert.Equal(t, client.Response{}, response)
+	})
+
+	t.Run("Arguments with whitespace are quoted", func(t *testing.T) {
+		require.NoError(t, cli.Set(ctx, "two words", "line\nbreak"))
+
+		value, err := cli.Get(ctx, "two words")
+		require.NoError(t, err)
+		assert.Equal(t, "line\nbreak", value)
+	})
+
+	t.Run("Invalid arguments are rejected locally", func(t *testing.T) {
+		assert.ErrorIs(t, cli.Set(ctx, "", "value"), client.ErrInvalidArgument)
+	})
+
+	t.Run("Concurrent calls share the pool", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				key := fmt.Sprintf("key%d", i)
+				assert.NoError(t, cli.Set(ctx, key, "value"))
+				value, err := cli.Get(ctx, key)
+				assert.NoError(t, err)
+				assert.Equal(t, "value", value)
+			}()
+		}
+		wg.Wait()
+	})
+
+	t.Run("Context deadline", func(t *testing.T) {
+		expired, cancel := context.WithTimeout(ctx, 0)
+		defer cancel()
+
+		assert.ErrorIs(t, cli.Ping(expired), context.DeadlineExceeded)
+	})
+}
+
+func TestClient_Reconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	var connections atomic.Int32
+
+	// Каждое нечетное соединение рвется на первом запросе после handshake
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			number := connections.Add(1)
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if strings.HasPrefix(line, network.HelloCommandToken) {
+						_, _ = conn.Write([]byte(`{"status":"ok"}` + "\n"))
+						continue
+					}
+					if number%2 == 1 {
+						return
+					}
+					_, _ = conn.Write([]byte(`{"status":"ok","value":"PONG"}` + "\n"))
+				}
+			}()
+		}
+	}()
+
+	newClient := func() *client.Client {
+		cli, err := client.New(client.Options{
+			Address:    listener.Addr().String(),
+			PoolSize:   1,
+			MaxRetries: 2,
+			MinBackoff: time.Millisecond,
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			require.NoError(t, cli.Close())
+		})
+		return cli
+	}
+
+	t.Run("Non-idempotent query is not retried", func(t *testing.T) {
+		cli := newClient()
+
+		_, err := cli.Do(context.Background(), "PING")
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), connections.Load())
+
+		// Следующий вызов переподключается
+		require.NoError(t, cli.Ping(context.Background()))
+		assert.Equal(t, int32(2), connections.Load())
+	})
+
+	t.Run("Idempotent query is retried on a new connection", func(t *testing.T) {
+		cli := newClient()
+
+		require.NoError(t, cli.Ping(context.Background()))
+		assert.Equal(t, int32(4), connections.Load())
+	})
+}
+
+func startServer(t *testing.T, handler network.RequestHandler) string {
+	t.Helper()
+
+	conf := &config.NetworkConfig{
+		MaxMessageSize: "4KB",
+		MaxConnections: 10,
+		RateLimit:      config.RateLimitConfig{Mode: network.RateLimitModeReject},
+	}
+
+	rateLimiter, err := network.NewRateLimiter(conf.RateLimit)
+	require.NoError(t, err)
+
+	server, err := network.NewTCPServer(zaptest.NewLogger(t), conf,
+		network.NewConnectionLimiter(conf.MaxConnections), rateLimiter, network.NewClientRegistry(), handler)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, server.Serve(ctx, listener))
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return listener.Addr().String()
+}
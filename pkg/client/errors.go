@@ -0,0 +1,94 @@
+package client
+
+import (
+	"concurrency_hw/internal/database/network"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotFound        = errors.New("key not found")
+	ErrParse           = errors.New("server cannot parse query")
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrStore           = errors.New("server cannot store command")
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrTooLarge        = errors.New("message too large")
+	ErrNoConnections   = errors.New("no connections available")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrForbidden       = errors.New("forbidden")
+	ErrThrottled       = errors.New("throttled")
+	ErrInternal        = errors.New("internal server error")
+	ErrClosed          = errors.New("client is closed")
+	ErrNotInteger      = errors.New("value is not an integer")
+	ErrOverflow        = errors.New("integer overflow")
+	ErrUnsupported     = errors.New("unsupported by server engine")
+	ErrWrongType       = errors.New("key holds a value of another type")
+)
+
+// Status - статус ошибки, которую вернул сервер
+type Status string
+
+const (
+	StatusNotFound        Status = "not_found"
+	StatusParseError      Status = "parse_error"
+	StatusUnknownCommand  Status = "unknown_command"
+	StatusStoreError      Status = "store_error"
+	StatusInvalidArgument Status = "invalid_argument"
+	StatusMessageTooLarge Status = "message_too_large"
+	StatusNoConnections   Status = "no_connections"
+	StatusUnauthenticated Status = "unauthenticated"
+	StatusForbidden       Status = "forbidden"
+	StatusThrottled       Status = "throttled"
+	StatusInternalError   Status = "internal_error"
+	StatusNotInteger      Status = "not_integer"
+	StatusOverflow        Status = "overflow"
+	StatusUnsupported     Status = "unsupported"
+	StatusWrongType       Status = "wrongtype"
+)
+
+var statusErrors = map[Status]error{
+	StatusNotFound:        ErrNotFound,
+	StatusParseError:      ErrParse,
+	StatusUnknownCommand:  ErrUnknownCommand,
+	StatusStoreError:      ErrStore,
+	StatusInvalidArgument: ErrInvalidArgument,
+	StatusMessageTooLarge: ErrTooLarge,
+	StatusNoConnections:   ErrNoConnections,
+	StatusUnauthenticated: ErrUnauthenticated,
+	StatusForbidden:       ErrForbidden,
+	StatusThrottled:       ErrThrottled,
+	StatusInternalError:   ErrInternal,
+	StatusNotInteger:      ErrNotInteger,
+	StatusOverflow:        ErrOverflow,
+	StatusUnsupported:     ErrUnsupported,
+	StatusWrongType:       ErrWrongType,
+}
+
+// ServerError - ошибка, которую вернул сервер. Сравнивается через errors.Is с sentinel-ошибкой своего статуса
+type ServerError struct {
+	Status  Status
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	if e.Message == "" {
+		return string(e.Status)
+	}
+	return fmt.Sprintf("%s: %s", e.Status, e.Message)
+}
+
+func (e *ServerError) Is(target error) bool {
+	return statusErrors[e.Status] == target
+}
+
+func responseError(response network.Response) error {
+	if response.Status == network.StatusOK {
+		return nil
+	}
+	return &ServerError{Status: Status(response.Status.String()), Message: response.Error}
+}
+
+// retryableStatus - запрос гарантированно не был выполнен сервером и его можно повторить
+func retryableStatus(err error) bool {
+	return errors.Is(err, ErrThrottled) || errors.Is(err, ErrNoConnections)
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"concurrency_hw/internal/database/compute"
+	"concurrency_hw/internal/database/network"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+type conn struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+	broken  bool
+}
+
+func (c *conn) roundTrip(ctx context.Context, query string, timeout time.Duration) (network.Response, error) {
+	deadline := time.Now().Add(timeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err := c.netConn.SetDeadline(deadline); err != nil {
+		c.broken = true
+		return network.Response{}, err
+	}
+
+	// Отмена контекста прерывает ожидание ответа
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.netConn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := c.netConn.Write([]byte(query + "\n")); err != nil {
+		c.broken = true
+		return network.Response{}, c.contextError(ctx, err)
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.broken = true
+		return network.Response{}, c.contextError(ctx, err)
+	}
+
+	response, err := network.DecodeResponse(network.JSONEncoding, bytes.TrimSuffix(line, []byte("\n")))
+	if err != nil {
+		c.broken = true
+		return network.Response{}, err
+	}
+
+	return response, nil
+}
+
+func (c *conn) contextError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (c *conn) close() error {
+	return c.netConn.Close()
+}
+
+// pool ограничивает число соединений и переиспользует простаивающие
+type pool struct {
+	opts   Options
+	slots  chan struct{}
+	mu     sync.Mutex
+	idle   []*conn
+	closed bool
+}
+
+func newPool(opts Options) *pool {
+	return &pool{
+		opts:  opts,
+		slots: make(chan struct{}, opts.PoolSize),
+		idle:  make([]*conn, 0, opts.PoolSize),
+	}
+}
+
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		<-p.slots
+		return nil, ErrClosed
+	}
+	if n := len(p.idle); n > 0 {
+		c := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	c, err := p.dial(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (p *pool) put(c *conn) {
+	defer func() {
+		<-p.slots
+	}()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if c.broken || p.closed {
+		_ = c.close()
+		return
+	}
+
+	p.idle = append(p.idle, c)
+}
+
+func (p *pool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+
+	var errs []error
+	for _, c := range p.idle {
+		errs = append(errs, c.close())
+	}
+	p.idle = nil
+
+	return errors.Join(errs...)
+}
+
+func (p *pool) dial(ctx context.Context) (*conn, error) {
+	dialer := net.Dialer{Timeout: p.opts.DialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", p.opts.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &conn{
+		netConn: netConn,
+		reader:  bufio.NewReader(netConn),
+	}
+
+	if err := p.handshake(ctx, c); err != nil {
+		_ = c.close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (p *pool) handshake(ctx context.Context, c *conn) error {
+	response, err := c.roundTrip(ctx, network.HelloCommandToken+" "+network.JSONEncoding.String(), p.opts.CallTimeout)
+	if err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+	if err := responseError(response); err != nil {
+		return fmt.Errorf("handshake failed: %w", err)
+	}
+
+	if p.opts.Username == "" {
+		return nil
+	}
+
+	response, err = c.roundTrip(ctx, query(compute.AuthCommandId, p.opts.Username, p.opts.Password), p.opts.CallTimeout)
+	if err != nil {
+		return fmt.Errorf("authentication failed: %w", err)
+	}
+
+	return responseError(response)
+}